@@ -0,0 +1,351 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// desktopEntry data type
+type desktopEntry struct {
+	id      string
+	file    string
+	name    string
+	icon    string
+	exec    string
+	hidden  bool
+	useTerm bool
+}
+
+// xdgDirs returns the directories listed in the environment variable {env} or {def}
+func xdgDirs(env string, def string) []string {
+	value := os.Getenv(env)
+	if value == "" {
+		value = def
+	}
+	var dirs []string
+	for _, dir := range filepath.SplitList(value) {
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// xdgHome returns the directory of the environment variable {env} or $HOME/{def}
+func xdgHome(env string, def string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, def)
+}
+
+// applicationDirs returns the directories where the desktop entries are installed
+func applicationDirs() []string {
+	var dirs []string
+	if dataHome := xdgHome("XDG_DATA_HOME", ".local/share"); dataHome != "" {
+		dirs = append(dirs, filepath.Join(dataHome, "applications"))
+	}
+	for _, dir := range xdgDirs("XDG_DATA_DIRS", "/usr/local/share:/usr/share") {
+		dirs = append(dirs, filepath.Join(dir, "applications"))
+	}
+	return dirs
+}
+
+// mimeappsLists returns the mimeapps.list files sorted by precedence
+func mimeappsLists() []string {
+	var files []string
+	if configHome := xdgHome("XDG_CONFIG_HOME", ".config"); configHome != "" {
+		files = append(files, filepath.Join(configHome, "mimeapps.list"))
+	}
+	for _, dir := range xdgDirs("XDG_CONFIG_DIRS", "/etc/xdg") {
+		files = append(files, filepath.Join(dir, "mimeapps.list"))
+	}
+	for _, dir := range applicationDirs() {
+		files = append(files, filepath.Join(dir, "mimeapps.list"))
+	}
+	return files
+}
+
+// desktopIDs returns the desktop entries associated with {mime}, the default applications
+// come first followed by the added associations and the ones declared in mimeinfo.cache
+func desktopIDs(mime string) []string {
+	var defaults, added []string
+	removed := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, list := range mimeappsLists() {
+		fd, err := os.Open(list)
+		if err != nil {
+			continue
+		}
+		var section string
+		scanner := bufio.NewScanner(fd)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+				section = line[1 : len(line)-1]
+				continue
+			}
+			idx := strings.Index(line, "=")
+			if idx == -1 || strings.TrimSpace(line[:idx]) != mime {
+				continue
+			}
+			for _, id := range strings.Split(line[idx+1:], ";") {
+				id = strings.TrimSpace(id)
+				if id == "" {
+					continue
+				}
+				switch section {
+				case "Default Applications":
+					if !seen[id] {
+						defaults = append(defaults, id)
+					}
+				case "Added Associations":
+					if !seen[id] {
+						added = append(added, id)
+					}
+				case "Removed Associations":
+					removed[id] = true
+				}
+				seen[id] = true
+			}
+		}
+		fd.Close()
+	}
+	var cached []string
+	for _, dir := range applicationDirs() {
+		for _, id := range mimeinfoCache(filepath.Join(dir, "mimeinfo.cache"), mime) {
+			if !seen[id] {
+				cached = append(cached, id)
+				seen[id] = true
+			}
+		}
+	}
+	var ids []string
+	for _, id := range append(append(defaults, added...), cached...) {
+		if !removed[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// mimeinfoCache returns the desktop entries that declare support for {mime} in a mimeinfo.cache file
+func mimeinfoCache(file string, mime string) []string {
+	var ids []string
+	fd, err := os.Open(file)
+	if err != nil {
+		return ids
+	}
+	defer fd.Close()
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		idx := strings.Index(line, "=")
+		if idx == -1 || line[:idx] != mime {
+			continue
+		}
+		for _, id := range strings.Split(line[idx+1:], ";") {
+			if id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// findDesktopEntry looks for the desktop entry {id} in the applications directories
+func findDesktopEntry(id string) (desktopEntry, error) {
+	for _, dir := range applicationDirs() {
+		file := filepath.Join(dir, id)
+		if _, err := os.Stat(file); err != nil {
+			// the desktop ID "foo-bar.desktop" may also be installed as "foo/bar.desktop"
+			file = filepath.Join(dir, strings.Replace(id, "-", "/", 1))
+			if _, err := os.Stat(file); err != nil {
+				continue
+			}
+		}
+		entry, err := readDesktopEntry(file)
+		if err != nil {
+			return entry, err
+		}
+		entry.id = id
+		return entry, nil
+	}
+	return desktopEntry{}, fmt.Errorf("findDesktopEntry: error: desktop entry '%s' was not found", id)
+}
+
+// readDesktopEntry reads the [Desktop Entry] group of a desktop file
+func readDesktopEntry(file string) (desktopEntry, error) {
+	entry := desktopEntry{file: file}
+	fd, err := os.Open(file)
+	if err != nil {
+		return entry, err
+	}
+	defer fd.Close()
+	var section string
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line[1 : len(line)-1]
+			continue
+		}
+		idx := strings.Index(line, "=")
+		if section != "Desktop Entry" || idx == -1 {
+			continue
+		}
+		value := strings.TrimSpace(line[idx+1:])
+		switch strings.TrimSpace(line[:idx]) {
+		case "Name":
+			entry.name = value
+		case "Icon":
+			entry.icon = value
+		case "Exec":
+			entry.exec = value
+		case "Hidden":
+			entry.hidden = value == "true"
+		case "Terminal":
+			entry.useTerm = value == "true"
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return entry, err
+	}
+	if entry.exec == "" {
+		return entry, fmt.Errorf("readDesktopEntry: error: '%s' has no Exec key", file)
+	}
+	return entry, nil
+}
+
+// desktopRule returns the opener rule of a desktop entry, expanding the Exec field codes
+func desktopRule(entry desktopEntry) (rule, error) {
+	r := rule{
+		useTerm: entry.useTerm,
+		source:  entry.file,
+	}
+	fields, err := splitExec(entry.exec)
+	if err != nil {
+		return r, fmt.Errorf("desktopRule: error: %s: %s", entry.file, err)
+	}
+	var args []string
+	hasFile := false
+	for _, field := range fields {
+		switch field {
+		case "%f", "%u":
+			args = append(args, "%f")
+			hasFile = true
+			continue
+		case "%F", "%U":
+			args = append(args, "%F")
+			hasFile = true
+			continue
+		case "%i":
+			if entry.icon != "" {
+				args = append(args, "--icon", entry.icon)
+			}
+			continue
+		case "%d", "%D", "%n", "%N", "%v", "%m":
+			continue
+		}
+		var arg strings.Builder
+		for i := 0; i < len(field); i++ {
+			if field[i] != '%' || i+1 == len(field) {
+				arg.WriteByte(field[i])
+				continue
+			}
+			i++
+			switch field[i] {
+			case '%':
+				arg.WriteString("%%")
+			case 'f', 'F', 'u', 'U':
+				arg.WriteString("%f")
+				hasFile = true
+			case 'c':
+				arg.WriteString(entry.name)
+			case 'k':
+				arg.WriteString(entry.file)
+			}
+		}
+		args = append(args, arg.String())
+	}
+	if len(args) == 0 {
+		return r, fmt.Errorf("desktopRule: error: %s: empty Exec key", entry.file)
+	}
+	if !hasFile {
+		args = append(args, "%f")
+	}
+	r.cmds = [][]string{args}
+	return r, nil
+}
+
+// splitExec splits the Exec key of a desktop entry, honouring its quoting rules
+func splitExec(str string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inQuote := false
+	inField := false
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		switch {
+		case inQuote && c == '\\' && i+1 < len(str):
+			i++
+			field.WriteByte(str[i])
+		case c == '"':
+			inQuote = !inQuote
+			inField = true
+		case !inQuote && (c == ' ' || c == '\t'):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteByte(c)
+			inField = true
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unterminated quote in '%s'", str)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+// mimeappsRules returns the opener rules of the applications associated with {mime}
+func mimeappsRules(mime string) []rule {
+	var rules []rule
+	if mime == "" {
+		return rules
+	}
+	for _, id := range desktopIDs(mime) {
+		entry, err := findDesktopEntry(id)
+		if err != nil || entry.hidden {
+			continue
+		}
+		r, err := desktopRule(entry)
+		if err != nil {
+			continue
+		}
+		r.matched = "mime:" + mime
+		rules = append(rules, r)
+	}
+	return rules
+}
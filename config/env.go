@@ -0,0 +1,125 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package config
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// terminals are the terminal emulators probed when $TERMINAL is not set, in order
+var terminals = []string{"foot", "alacritty", "kitty", "gnome-terminal", "xterm"}
+
+// terminalFlags maps a terminal emulator to the arguments that precede the command to execute
+var terminalFlags = map[string][]string{
+	"alacritty":      {"-e"},
+	"foot":           {},
+	"gnome-terminal": {"--"},
+	"kitty":          {},
+	"konsole":        {"-e"},
+	"st":             {"-e"},
+	"terminator":     {"-x"},
+	"urxvt":          {"-e"},
+	"wezterm":        {"start", "--"},
+	"xfce4-terminal": {"-x"},
+	"xterm":          {"-e"},
+}
+
+// guiEditors are the editors that open their own window, so they do not need a terminal
+var guiEditors = map[string]bool{
+	"code":              true,
+	"codium":            true,
+	"gedit":             true,
+	"gnome-text-editor": true,
+	"gvim":              true,
+	"kate":              true,
+	"mousepad":          true,
+	"subl":              true,
+	"xed":               true,
+}
+
+// envCommand returns the command of the first environment variable that is set
+func envCommand(envs ...string) []string {
+	for _, env := range envs {
+		if value := os.Getenv(env); value != "" {
+			if fields, err := SplitFields(value); err == nil && len(fields) > 0 {
+				return fields
+			}
+		}
+	}
+	return nil
+}
+
+// editorCmd returns the text editor from $VISUAL or $EDITOR, vim otherwise
+func editorCmd() []string {
+	if cmd := envCommand("VISUAL", "EDITOR"); cmd != nil {
+		return cmd
+	}
+	return []string{"vim"}
+}
+
+// pagerCmd returns the pager from $PAGER, the text editor otherwise
+func pagerCmd() []string {
+	if cmd := envCommand("PAGER"); cmd != nil {
+		return cmd
+	}
+	return editorCmd()
+}
+
+// positionArgs maps an editor to the arguments that open a file at a line and column
+var positionArgs = map[string][]string{
+	"emacs":       {"+%l:%c"},
+	"emacsclient": {"+%l:%c"},
+	"gvim":        {"+%l"},
+	"kak":         {"+%l:%c"},
+	"micro":       {"+%l:%c"},
+	"nano":        {"+%l,%c"},
+	"nvim":        {"+%l"},
+	"vi":          {"+%l"},
+	"vim":         {"+%l"},
+}
+
+// multiFiles returns the command that opens several files at once, with vim in
+// tabs, and at the given position when the program is a known editor
+func multiFiles(cmd []string) []string {
+	multi := append([]string{}, cmd...)
+	switch filepath.Base(cmd[0]) {
+	case "gvim", "nvim", "vim":
+		multi = append(multi, "-p")
+	}
+	multi = append(multi, positionArgs[filepath.Base(cmd[0])]...)
+	return append(multi, "%F")
+}
+
+// needsTerm reports whether the program runs inside a terminal
+func needsTerm(prg string) bool {
+	return !guiEditors[filepath.Base(prg)]
+}
+
+// terminal returns the terminal emulator from $TERMINAL or the first one installed, xterm otherwise
+func terminal() string {
+	if cmd := envCommand("TERMINAL"); cmd != nil {
+		return cmd[0]
+	}
+	for _, term := range terminals {
+		if _, err := exec.LookPath(term); err == nil {
+			return term
+		}
+	}
+	return "xterm"
+}
+
+// terminalArgs returns the arguments that make {term} execute a command,
+// preceded by the extra arguments given in $TERMINAL
+func terminalArgs(term string) []string {
+	var args []string
+	if cmd := envCommand("TERMINAL"); cmd != nil && cmd[0] == term {
+		args = append(args, cmd[1:]...)
+	}
+	if flags, found := terminalFlags[filepath.Base(term)]; found {
+		return append(args, flags...)
+	}
+	return append(args, "-e")
+}
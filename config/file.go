@@ -0,0 +1,289 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The configuration file is read line by line, empty lines and lines starting
+// with '#' are ignored. Settings use the form "key = value":
+//
+//	term = xterm
+//	termargs = -e
+//	termmode = auto
+//	muxtarget = window
+//	editorserver = off
+//	servername = sf
+//	nvimsocket = /tmp/sf.nvim
+//	log = ~/.cache/sf.log
+//	mimeapps = fallback
+//
+// By default the terminal is taken from $TERMINAL or else the first one
+// installed among foot, alacritty, kitty, gnome-terminal and xterm, and its
+// arguments are the ones that terminal needs to execute a command, so
+// termargs is only required for the terminals that sf does not know. Text
+// files are opened with $VISUAL or $EDITOR (vim otherwise), and log files
+// with $PAGER when it is set.
+//
+// The termmode setting controls how the programs that need a terminal are
+// run: "window" opens them in a new terminal window, "inline" suspends sf
+// and runs them in the current terminal, "tmux" and "screen" open them in
+// the terminal multiplexer. The "auto" mode uses tmux when $TMUX is set,
+// screen when $STY is set, inline when neither $DISPLAY nor $WAYLAND_DISPLAY
+// are set, and window otherwise. The muxtarget setting chooses where the
+// multiplexer opens the program: a new "window", a "hsplit" pane side by side
+// or a "vsplit" pane stacked below the current one.
+//
+// With editorserver on, the files opened with vim, gvim, nvim or emacs are
+// sent to an already running instance of the editor: the vim clientserver
+// named servername, the neovim listening on $NVIM or nvimsocket, or the
+// emacs server through emacsclient. When no server is reachable a new
+// editor is started, acting as the server for the next files when possible.
+//
+// The mimeapps setting controls the freedesktop mimeapps.list lookup: with
+// "prefer" it is tried after the user rules and before the built-in ones,
+// with "fallback" it is tried last and with "off" it is never used.
+//
+// Opener rules use the form "open matcher [options] = program [args]":
+//
+//	open ext:.pdf = zathura
+//	open ext:.mp3,.ogg = mpv --no-video
+//	open ext:.md term = vim
+//	open ext:.jpg,.png wait = feh %F
+//	open mime:image/*,application/pdf = xdg-open
+//	open name:Makefile,Dockerfile term = vim
+//	open ext:.tex = latexmk -pdf %f
+//	open ext:.go term = vim +%l %f
+//
+// The matchers, the options and the precedence of the rules are described in
+// rules.go. The file is appended to the command unless it contains the
+// placeholder %f, or the argument %F that is replaced by all the files when
+// several files are opened at once. When a file is given as path:line[:col]
+// the placeholders %l and %c are replaced by its line and column, otherwise
+// the arguments that contain them are dropped. A literal '%' is written as %%.
+
+// termArgsSet reports whether termargs was set in the configuration file
+var termArgsSet bool
+
+// Load reads the user configuration file, a missing file is not an error
+func Load(file string) error {
+	fd, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer fd.Close()
+	var rules []rule
+	scanner := bufio.NewScanner(fd)
+	for num := 1; scanner.Scan(); num++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idx := strings.Index(line, "=")
+		if idx == -1 {
+			return fmt.Errorf("load: error: %s:%d: missing '=' in '%s'\n", file, num, line)
+		}
+		key := strings.TrimSpace(line[:idx])
+		value := strings.TrimSpace(line[idx+1:])
+		if strings.HasPrefix(key, "open ") {
+			r, errPr := parseRule(key, value)
+			if errPr != nil {
+				return fmt.Errorf("load: error: %s:%d: %s\n", file, num, errPr)
+			}
+			r.source = file
+			r.line = num
+			rules = append(rules, r)
+			continue
+		}
+		if errPs := parseSetting(key, value); errPs != nil {
+			return fmt.Errorf("load: error: %s:%d: %s\n", file, num, errPs)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	userRules = rules
+	return nil
+}
+
+// Remember saves the opener as a rule for the extension {ext} in the configuration file,
+// the rule is written before the other ones so that it takes precedence
+func Remember(ext string, opener Opener) error {
+	if ext == "" {
+		return errors.New("remember: error: the file has no extension")
+	}
+	// a matcher ends at a blank, lists patterns separated by ',' and the key ends at '='
+	if strings.ContainsAny(ext, " \t,=") {
+		return fmt.Errorf("remember: error: the extension '%s' cannot be written as a rule", ext)
+	}
+	key := "open ext:" + ext
+	if opener.UseTerm {
+		key += " term"
+	}
+	if opener.Wait {
+		key += " wait"
+	}
+	value := JoinFields(append([]string{opener.Name}, opener.Args...))
+	if _, errPr := parseRule(key, value); errPr != nil {
+		return fmt.Errorf("remember: error: %s", errPr)
+	}
+	newLine := fmt.Sprintf("%s = %s", key, value)
+	content, err := ioutil.ReadFile(ConfigFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var lines []string
+	written := false
+	for _, line := range strings.Split(strings.TrimRight(string(content), "\n"), "\n") {
+		if !written && strings.HasPrefix(strings.TrimSpace(line), "open ") {
+			lines = append(lines, newLine)
+			written = true
+		}
+		lines = append(lines, line)
+	}
+	if !written {
+		lines = append(lines, newLine)
+	}
+	if errMa := os.MkdirAll(filepath.Dir(ConfigFile), 0700); errMa != nil {
+		return errMa
+	}
+	if errWf := ioutil.WriteFile(ConfigFile, []byte(strings.TrimLeft(strings.Join(lines, "\n"), "\n")+"\n"), 0600); errWf != nil {
+		return errWf
+	}
+	return Load(ConfigFile)
+}
+
+// parseSetting sets the global setting {key} to {value}
+func parseSetting(key string, value string) error {
+	if value == "" {
+		return fmt.Errorf("empty value for '%s'", key)
+	}
+	switch key {
+	case "term":
+		Term = value
+		if !termArgsSet {
+			TermArgs = terminalArgs(value)
+		}
+	case "termargs":
+		args, err := SplitFields(value)
+		if err != nil {
+			return err
+		}
+		TermArgs = args
+		termArgsSet = true
+	case "log":
+		SFLog = expandPath(value)
+	case "termmode":
+		switch value {
+		case "auto", "inline", "screen", "tmux", "window":
+			TermMode = value
+		default:
+			return fmt.Errorf("invalid value '%s' for '%s', expected auto, inline, screen, tmux or window", value, key)
+		}
+	case "muxtarget":
+		switch value {
+		case "hsplit", "vsplit", "window":
+			MuxTarget = value
+		default:
+			return fmt.Errorf("invalid value '%s' for '%s', expected hsplit, vsplit or window", value, key)
+		}
+	case "editorserver":
+		switch value {
+		case "on", "off":
+			EditorServer = value == "on"
+		default:
+			return fmt.Errorf("invalid value '%s' for '%s', expected on or off", value, key)
+		}
+	case "servername":
+		ServerName = value
+	case "nvimsocket":
+		NvimSocket = expandPath(value)
+	case "mimeapps":
+		switch value {
+		case "fallback", "prefer", "off":
+			Mimeapps = value
+		default:
+			return fmt.Errorf("invalid value '%s' for '%s', expected fallback, prefer or off", value, key)
+		}
+	default:
+		return fmt.Errorf("unknown setting '%s'", key)
+	}
+	return nil
+}
+
+// SplitFields splits a command line into fields, honouring single and double quotes
+func SplitFields(str string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	var quote rune
+	inField := false
+	for _, c := range str {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			field.WriteRune(c)
+		case c == '\'' || c == '"':
+			quote = c
+			inField = true
+		case c == ' ' || c == '\t':
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(c)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in '%s'", str)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+// JoinFields joins the fields of a command line, quoting the ones that need it so
+// that SplitFields returns them unchanged
+func JoinFields(fields []string) string {
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		if field == "" || strings.ContainsAny(field, " \t'\"|") {
+			switch {
+			case !strings.Contains(field, "'"):
+				field = "'" + field + "'"
+			case !strings.Contains(field, `"`):
+				field = `"` + field + `"`
+			default:
+				// the single quotes are written between double quotes: 'it'"'"'s'
+				field = "'" + strings.ReplaceAll(field, "'", `'"'"'`) + "'"
+			}
+		}
+		quoted[i] = field
+	}
+	return strings.Join(quoted, " ")
+}
+
+// expandPath expands environment variables and a leading '~' in {path}
+func expandPath(path string) string {
+	path = os.ExpandEnv(path)
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(homeDir, path[1:])
+		}
+	}
+	return path
+}
@@ -0,0 +1,75 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package config
+
+import "testing"
+
+func TestJoinFields(t *testing.T) {
+	tests := []struct {
+		fields []string
+		line   string
+	}{
+		{[]string{"vim", "-p", "%F"}, "vim -p %F"},
+		{[]string{"a b"}, "'a b'"},
+		{[]string{"a\tb"}, "'a\tb'"},
+		{[]string{"it's"}, `"it's"`},
+		{[]string{`say "x"`}, `'say "x"'`},
+		{[]string{`it's "x"`}, `'it'"'"'s "x"'`},
+		{[]string{"sh", "-c", "ls | less"}, "sh -c 'ls | less'"},
+		{[]string{"a|b"}, "'a|b'"},
+		{[]string{"prg", "", "x"}, "prg '' x"},
+		{[]string{""}, "''"},
+	}
+	for _, tt := range tests {
+		line := JoinFields(tt.fields)
+		if line != tt.line {
+			t.Errorf("JoinFields(%q) = %q, want %q", tt.fields, line, tt.line)
+		}
+		fields, err := SplitFields(line)
+		if err != nil || !equalFields(fields, tt.fields) {
+			t.Errorf("SplitFields(%q) = %q, %v, want %q", line, fields, err, tt.fields)
+		}
+		// the line is written as the command of a rule by Remember
+		r, err := parseRule("open ext:.x", line)
+		if err != nil || len(r.cmds) != 1 || !equalFields(r.cmds[0], tt.fields) {
+			t.Errorf("parseRule(%q) = %q, %v, want %q", line, r.cmds, err, tt.fields)
+		}
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	tests := []struct {
+		line   string
+		fields []string
+		err    bool
+	}{
+		{"", nil, false},
+		{"  vim   -p  ", []string{"vim", "-p"}, false},
+		{`a"b c"d`, []string{"ab cd"}, false},
+		{`'' ""`, []string{"", ""}, false},
+		{`'a "b"'`, []string{`a "b"`}, false},
+		{`"it's"`, []string{"it's"}, false},
+		{"'a", nil, true},
+		{`"a`, nil, true},
+	}
+	for _, tt := range tests {
+		fields, err := SplitFields(tt.line)
+		if (err != nil) != tt.err || !equalFields(fields, tt.fields) {
+			t.Errorf("SplitFields(%q) = %q, %v, want %q, error %v", tt.line, fields, err, tt.fields, tt.err)
+		}
+	}
+}
+
+// equalFields reports whether the fields {a} and {b} are the same
+func equalFields(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
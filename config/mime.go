@@ -0,0 +1,175 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sniffLen is the number of bytes read to detect the file type
+const sniffLen = 512
+
+// signatures are the magic numbers not recognized by http.DetectContentType
+var signatures = []struct {
+	magic []byte
+	mime  string
+}{
+	{[]byte("\x7fELF"), "application/x-executable"},
+	{[]byte("\xfe\xed\xfa\xce"), "application/x-mach-binary"},
+	{[]byte("\xfe\xed\xfa\xcf"), "application/x-mach-binary"},
+	{[]byte("\xce\xfa\xed\xfe"), "application/x-mach-binary"},
+	{[]byte("\xcf\xfa\xed\xfe"), "application/x-mach-binary"},
+	{[]byte("\x1f\x8b"), "application/gzip"},
+	{[]byte("BZh"), "application/x-bzip2"},
+	{[]byte("\xfd7zXZ\x00"), "application/x-xz"},
+	{[]byte("\x28\xb5\x2f\xfd"), "application/zstd"},
+	{[]byte("7z\xbc\xaf\x27\x1c"), "application/x-7z-compressed"},
+	{[]byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1"), "application/x-ole-storage"},
+	{[]byte("SQLite format 3\x00"), "application/vnd.sqlite3"},
+	{[]byte("\x1a\x45\xdf\xa3"), "video/x-matroska"},
+	{[]byte("fLaC"), "audio/flac"},
+	{[]byte("II*\x00"), "image/tiff"},
+	{[]byte("MM\x00*"), "image/tiff"},
+}
+
+// interpreters maps the interpreter of a script to its MIME type
+var interpreters = map[string]string{
+	"ash":    "text/x-shellscript",
+	"bash":   "text/x-shellscript",
+	"dash":   "text/x-shellscript",
+	"ksh":    "text/x-shellscript",
+	"sh":     "text/x-shellscript",
+	"zsh":    "text/x-shellscript",
+	"awk":    "text/x-awk",
+	"lua":    "text/x-lua",
+	"node":   "text/javascript",
+	"perl":   "text/x-perl",
+	"php":    "text/x-php",
+	"python": "text/x-python",
+	"ruby":   "text/x-ruby",
+	"tclsh":  "text/x-tcl",
+}
+
+// DetectMIME returns the MIME type of a file based on its content
+func DetectMIME(file string) (string, error) {
+	fi, err := os.Stat(file)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("detectMIME: error: '%s' no such file or directory\n", file)
+	} else if err != nil {
+		return "", err
+	}
+	switch mode := fi.Mode(); {
+	case mode.IsDir():
+		return "inode/directory", nil
+	case mode&os.ModeNamedPipe != 0:
+		return "inode/fifo", nil
+	case mode&os.ModeSocket != 0:
+		return "inode/socket", nil
+	case mode&os.ModeCharDevice != 0:
+		return "inode/chardevice", nil
+	case mode&os.ModeDevice != 0:
+		return "inode/blockdevice", nil
+	}
+	fd, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer fd.Close()
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(fd, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return sniff(buf[:n]), nil
+}
+
+// sniff returns the MIME type of {data}
+func sniff(data []byte) string {
+	for _, sig := range signatures {
+		if bytes.HasPrefix(data, sig.magic) {
+			return sig.mime
+		}
+	}
+	if bytes.HasPrefix(data, []byte("#!")) {
+		return shebang(data)
+	}
+	mime := http.DetectContentType(data)
+	if idx := strings.Index(mime, ";"); idx != -1 {
+		mime = mime[:idx]
+	}
+	return mime
+}
+
+// shebang returns the MIME type of a script based on its interpreter
+func shebang(data []byte) string {
+	line := data[2:]
+	if idx := bytes.IndexByte(line, '\n'); idx != -1 {
+		line = line[:idx]
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return "text/x-script"
+	}
+	interp := filepath.Base(fields[0])
+	if interp == "env" {
+		interp = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interp = filepath.Base(field)
+				break
+			}
+		}
+	}
+	interp = strings.TrimRight(interp, "0123456789.")
+	if mime, found := interpreters[interp]; found {
+		return mime
+	}
+	return "text/x-script"
+}
+
+// binaryMIMEs are the MIME types whose content cannot be text
+var binaryMIMEs = []string{
+	"application/gzip",
+	"application/pdf",
+	"application/vnd.sqlite3",
+	"application/x-7z-compressed",
+	"application/x-bzip2",
+	"application/x-executable",
+	"application/x-mach-binary",
+	"application/x-ole-storage",
+	"application/x-xz",
+	"application/zip",
+	"application/zstd",
+	"audio/*",
+	"font/*",
+	"image/*",
+	"video/*",
+}
+
+// binaryMIME reports whether {mime} is the type of a binary file
+func binaryMIME(mime string) bool {
+	for _, pattern := range binaryMIMEs {
+		if matchMIME(pattern, mime) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMIME reports whether {mime} matches {pattern}, such as "image/*"
+func matchMIME(pattern string, mime string) bool {
+	if mime == "" {
+		return false
+	}
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(mime, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == mime
+}
@@ -0,0 +1,333 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrNoOpener is returned by a Resolver when it has no opener for the file
+var ErrNoOpener = errors.New("no opener was found")
+
+// NotInstalledError is returned by a Resolver when it has openers for the file
+// but none of their programs is installed
+type NotInstalledError struct {
+	Tried []string
+}
+
+// Error returns the programs that were tried
+func (e *NotInstalledError) Error() string {
+	return fmt.Sprintf("none of the programs is installed, tried: %s", strings.Join(e.Tried, ", "))
+}
+
+// Is reports that a NotInstalledError is also an ErrNoOpener
+func (e *NotInstalledError) Is(target error) bool {
+	return target == ErrNoOpener
+}
+
+// Opener is a program that opens a file
+type Opener struct {
+	// Name is the program to run
+	Name string
+	// Args are the program arguments, the placeholder %f is replaced by the file
+	Args []string
+	// UseTerm runs the program inside a terminal
+	UseTerm bool
+	// Wait blocks until the program finishes
+	Wait bool
+	// MIME is the MIME type detected from the file content
+	MIME string
+	// Rule is the matcher of the rule that selected the opener, such as "ext:.pdf"
+	Rule string
+	// Source is where the opener was defined: "builtin", a configuration file
+	// line or a desktop entry
+	Source string
+}
+
+// Resolver returns the opener of a file
+type Resolver interface {
+	Resolve(path string) (Opener, error)
+}
+
+// CandidateResolver is a Resolver that can also return every opener of a file,
+// sorted by preference
+type CandidateResolver interface {
+	Resolver
+	Candidates(path string) ([]Opener, error)
+}
+
+// Chain is a Resolver that tries its resolvers in order
+type Chain []Resolver
+
+// Resolve returns the opener of the first resolver that has one
+func (c Chain) Resolve(path string) (Opener, error) {
+	var tried []string
+	for _, resolver := range c {
+		opener, err := resolver.Resolve(path)
+		if err == nil {
+			return opener, nil
+		}
+		if !errors.Is(err, ErrNoOpener) {
+			return opener, err
+		}
+		var errNi *NotInstalledError
+		if errors.As(err, &errNi) {
+			tried = append(tried, errNi.Tried...)
+		}
+	}
+	mime, errDm := DetectMIME(path)
+	if errDm != nil {
+		return Opener{}, errDm
+	}
+	if len(tried) > 0 {
+		return Opener{MIME: mime}, fmt.Errorf("resolve: error: no program to open '%s' (%s) was found: %w", path, mime, &NotInstalledError{tried})
+	}
+	return Opener{MIME: mime}, fmt.Errorf("resolve: error: file format '%s' (%s) is not supported: %w", filepath.Ext(path), mime, ErrNoOpener)
+}
+
+// Candidates returns the openers of all the resolvers, without duplicates
+func (c Chain) Candidates(path string) ([]Opener, error) {
+	var candidates []Opener
+	seen := make(map[string]bool)
+	for _, resolver := range c {
+		var openers []Opener
+		if cr, ok := resolver.(CandidateResolver); ok {
+			var err error
+			if openers, err = cr.Candidates(path); err != nil {
+				return candidates, err
+			}
+		} else {
+			opener, err := resolver.Resolve(path)
+			if err != nil && !errors.Is(err, ErrNoOpener) {
+				return candidates, err
+			}
+			if err == nil {
+				openers = []Opener{opener}
+			}
+		}
+		for _, opener := range openers {
+			cmdLine := JoinFields(append([]string{opener.Name}, opener.Args...))
+			if !seen[cmdLine] {
+				seen[cmdLine] = true
+				candidates = append(candidates, opener)
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// Position is a line and column of a file, 0 means unknown
+type Position struct {
+	Line int
+	Col  int
+}
+
+// positionRe matches a trailing ":line[:col]", as in compiler errors and grep output
+var positionRe = regexp.MustCompile(`^(.+?):([0-9]+)(?::([0-9]+))?:?$`)
+
+// SplitPosition splits a trailing ":line[:col]" from {path} when the literal path does not exist
+func SplitPosition(path string) (string, Position) {
+	if _, err := os.Lstat(path); err == nil {
+		return path, Position{}
+	}
+	match := positionRe.FindStringSubmatch(path)
+	if match == nil {
+		return path, Position{}
+	}
+	if _, err := os.Lstat(match[1]); err != nil {
+		return path, Position{}
+	}
+	var pos Position
+	pos.Line, _ = strconv.Atoi(match[2])
+	if match[3] != "" {
+		pos.Col, _ = strconv.Atoi(match[3])
+	}
+	return match[1], pos
+}
+
+// Argv returns the command line that opens the files, when the opener
+// does not accept several files only the first one is used
+func (o Opener) Argv(files ...string) []string {
+	return o.ArgvAt(Position{}, files...)
+}
+
+// ArgvAt returns the command line that opens the files at the position {pos}
+func (o Opener) ArgvAt(pos Position, files ...string) []string {
+	if !o.Multi() && len(files) > 1 {
+		files = files[:1]
+	}
+	return append([]string{o.Name}, expandArgs(o.Args, files, pos)...)
+}
+
+// Multi reports whether the opener accepts several files in one invocation
+func (o Opener) Multi() bool {
+	for _, arg := range o.Args {
+		if arg == "%F" {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleResolver resolves the openers from a list of rules
+type ruleResolver struct {
+	rules func() []rule
+}
+
+// Resolve returns the first installed opener of the most specific rule that matches the file
+func (rr ruleResolver) Resolve(path string) (Opener, error) {
+	mime, _ := DetectMIME(path)
+	var tried []string
+	for _, r := range matchRules(rr.rules(), path, mime) {
+		openers, rTried := r.openers(mime)
+		if len(openers) > 0 {
+			return openers[0], nil
+		}
+		tried = append(tried, rTried...)
+	}
+	if len(tried) > 0 {
+		return Opener{}, &NotInstalledError{tried}
+	}
+	return Opener{}, ErrNoOpener
+}
+
+// Candidates returns the installed openers of all the rules that match the file
+func (rr ruleResolver) Candidates(path string) ([]Opener, error) {
+	var candidates []Opener
+	mime, _ := DetectMIME(path)
+	for _, r := range matchRules(rr.rules(), path, mime) {
+		openers, _ := r.openers(mime)
+		candidates = append(candidates, openers...)
+	}
+	return candidates, nil
+}
+
+// UserResolver returns the resolver of the rules read from the configuration file
+func UserResolver() CandidateResolver {
+	return ruleResolver{func() []rule { return userRules }}
+}
+
+// BuiltinResolver returns the resolver of the default opener table
+func BuiltinResolver() CandidateResolver {
+	return ruleResolver{func() []rule { return builtinRules }}
+}
+
+// mimeappsResolver resolves the openers from the freedesktop mimeapps.list files
+type mimeappsResolver struct{}
+
+// Resolve returns the opener of the default application for the file
+func (mr mimeappsResolver) Resolve(path string) (Opener, error) {
+	candidates, err := mr.Candidates(path)
+	if err != nil {
+		return Opener{}, err
+	}
+	if len(candidates) == 0 {
+		return Opener{}, ErrNoOpener
+	}
+	return candidates[0], nil
+}
+
+// Candidates returns the installed openers of all the applications associated with the file
+func (mr mimeappsResolver) Candidates(path string) ([]Opener, error) {
+	var candidates []Opener
+	mime, errDm := DetectMIME(path)
+	if errDm != nil {
+		return candidates, nil
+	}
+	for _, r := range mimeappsRules(mime) {
+		openers, _ := r.openers(mime)
+		candidates = append(candidates, openers...)
+	}
+	return candidates, nil
+}
+
+// MimeappsResolver returns the resolver of the freedesktop mimeapps.list files
+func MimeappsResolver() CandidateResolver {
+	return mimeappsResolver{}
+}
+
+// DefaultResolver returns the resolvers used by sf: the user rules, the
+// built-in rules and the mimeapps.list files according to the mimeapps setting
+func DefaultResolver() Chain {
+	chain := Chain{UserResolver()}
+	if Mimeapps == "prefer" {
+		chain = append(chain, MimeappsResolver())
+	}
+	chain = append(chain, BuiltinResolver())
+	if Mimeapps == "fallback" {
+		chain = append(chain, MimeappsResolver())
+	}
+	return chain
+}
+
+// ProgExt returns the program associated by their name, their extension or their content
+func ProgExt(file string) (Opener, error) {
+	return DefaultResolver().Resolve(file)
+}
+
+// ProgCandidates returns all the programs that can open the file
+func ProgCandidates(file string) ([]Opener, error) {
+	if _, errDm := DetectMIME(file); errDm != nil {
+		return nil, errDm
+	}
+	return DefaultResolver().Candidates(file)
+}
+
+// expandArgs replaces the placeholder %f by the first file and the argument %F
+// by all the files in {args}, or appends the files when there is none. The
+// placeholders %l and %c are replaced by the line and the column, the arguments
+// that contain them are dropped when the position is unknown
+func expandArgs(args []string, files []string, pos Position) []string {
+	var expanded []string
+	hasFile := false
+	col := pos.Col
+	if col == 0 {
+		col = 1
+	}
+	for _, arg := range args {
+		if arg == "%F" {
+			expanded = append(expanded, files...)
+			hasFile = true
+			continue
+		}
+		if pos.Line == 0 && (strings.Contains(arg, "%l") || strings.Contains(arg, "%c")) {
+			continue
+		}
+		var exp strings.Builder
+		for i := 0; i < len(arg); i++ {
+			if arg[i] != '%' || i+1 == len(arg) {
+				exp.WriteByte(arg[i])
+				continue
+			}
+			i++
+			switch arg[i] {
+			case 'f':
+				if len(files) > 0 {
+					exp.WriteString(files[0])
+				}
+				hasFile = true
+			case 'l':
+				exp.WriteString(strconv.Itoa(pos.Line))
+			case 'c':
+				exp.WriteString(strconv.Itoa(col))
+			case '%':
+				exp.WriteByte('%')
+			default:
+				exp.WriteByte('%')
+				exp.WriteByte(arg[i])
+			}
+		}
+		expanded = append(expanded, exp.String())
+	}
+	if !hasFile {
+		expanded = append(expanded, files...)
+	}
+	return expanded
+}
@@ -0,0 +1,282 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package config
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// An opener rule has one matcher followed by a comma separated list of patterns:
+//
+//	ext:.pdf,.tar.gz    file name suffixes, case insensitive
+//	name:Makefile,*.1   shell globs matched against the base name
+//	iname:readme*       like name, but case insensitive
+//	path:^/srv/.*\.log$ regular expression matched against the absolute path
+//	mime:image/*        MIME types detected from the file content
+//
+// The option "term" runs the program inside Term, and the option "wait"
+// blocks sf until the program finishes and then re-reads the directory, it
+// has no effect when the program is opened in a terminal multiplexer.
+// The command may list fallback programs separated by '|', the first one
+// installed is used:
+//
+//	open ext:.pdf = mupdf -A 8 | zathura | evince
+//
+// User rules are always tried before the built-in ones. Within the same
+// group the most specific rule wins: name and iname globs first, then path
+// regular expressions, then extensions (the longest suffix first, so .tar.gz
+// is preferred over .gz) and finally MIME types (exact types before the
+// "type/*" wildcards). Rules of the same specificity are tried in order.
+//
+// The names and extensions of the built-in rules for text and log files are
+// ignored when the content of the file is binary, such as an image, a PDF
+// document or an executable, so a PNG named photo.txt or a compressed log named
+// app.log.2.gz are opened by the rule of their MIME type instead of the editor.
+
+// rule data type, a rule with autoTerm runs each of its commands in a terminal
+// unless it is a graphical program
+type rule struct {
+	exts     []string
+	names    []string
+	inames   []string
+	paths    []*regexp.Regexp
+	mimes    []string
+	cmds     [][]string
+	useTerm  bool
+	autoTerm bool
+	wait     bool
+	text     bool
+	source   string
+	line     int
+	matched  string
+}
+
+// userRules are the opener rules read from the configuration file
+var userRules []rule
+
+// specificity scores of the matchers, an extension score is increased by its length
+const (
+	scoreMIME  = 1000
+	scoreExt   = 2000
+	scorePath  = 3000
+	scoreGlob  = 4000
+	scoreExact = 1
+)
+
+// score returns how specifically the rule matches the file, 0 means no match,
+// and the matcher that matched
+func (r rule) score(file string, mime string) (int, string) {
+	score := 0
+	matched := ""
+	base := filepath.Base(file)
+	lowerBase := strings.ToLower(base)
+	// the names and extensions of a text rule are misleading when the content is binary
+	names, inames, exts := r.names, r.inames, r.exts
+	if r.text && binaryMIME(mime) {
+		names, inames, exts = nil, nil, nil
+	}
+	for _, name := range names {
+		if ok, _ := filepath.Match(name, base); ok && score < scoreGlob {
+			score, matched = scoreGlob, "name:"+name
+		}
+	}
+	for _, iname := range inames {
+		if ok, _ := filepath.Match(strings.ToLower(iname), lowerBase); ok && score < scoreGlob {
+			score, matched = scoreGlob, "iname:"+iname
+		}
+	}
+	if len(r.paths) > 0 {
+		absFile, err := filepath.Abs(file)
+		if err == nil {
+			for _, re := range r.paths {
+				if re.MatchString(absFile) && score < scorePath {
+					score, matched = scorePath, "path:"+re.String()
+				}
+			}
+		}
+	}
+	for _, ext := range exts {
+		if len(lowerBase) > len(ext) && strings.HasSuffix(lowerBase, strings.ToLower(ext)) && score < scoreExt+len(ext) {
+			score, matched = scoreExt+len(ext), "ext:"+ext
+		}
+	}
+	for _, m := range r.mimes {
+		if matchMIME(m, mime) {
+			mimeScore := scoreMIME
+			if m == mime {
+				mimeScore += scoreExact
+			}
+			if score < mimeScore {
+				score, matched = mimeScore, "mime:"+m
+			}
+		}
+	}
+	return score, matched
+}
+
+// matchRules returns all the rules that match the file, the most specific first
+func matchRules(rules []rule, file string, mime string) []rule {
+	var matched []rule
+	var scores []int
+	for _, r := range rules {
+		if score, matcher := r.score(file, mime); score > 0 {
+			r.matched = matcher
+			matched = append(matched, r)
+			scores = append(scores, score)
+		}
+	}
+	sort.Stable(byScore{matched, scores})
+	return matched
+}
+
+// byScore sorts the rules by their score in descending order
+type byScore struct {
+	rules  []rule
+	scores []int
+}
+
+func (b byScore) Len() int           { return len(b.rules) }
+func (b byScore) Less(i, j int) bool { return b.scores[i] > b.scores[j] }
+func (b byScore) Swap(i, j int) {
+	b.rules[i], b.rules[j] = b.rules[j], b.rules[i]
+	b.scores[i], b.scores[j] = b.scores[j], b.scores[i]
+}
+
+// parseRule parses an opener rule, {key} is "open matcher [options]" and {value} the command
+func parseRule(key string, value string) (rule, error) {
+	var r rule
+	fields := strings.Fields(key)
+	if len(fields) < 2 {
+		return r, fmt.Errorf("missing matcher in '%s'", key)
+	}
+	matcher := fields[1]
+	idx := strings.Index(matcher, ":")
+	if idx == -1 || idx == len(matcher)-1 {
+		return r, fmt.Errorf("invalid matcher '%s'", matcher)
+	}
+	kind := matcher[:idx]
+	// path regular expressions may contain commas
+	patterns := []string{matcher[idx+1:]}
+	if kind != "path" {
+		patterns = strings.Split(matcher[idx+1:], ",")
+	}
+	for _, pattern := range patterns {
+		if pattern == "" {
+			return r, fmt.Errorf("empty pattern in '%s'", matcher)
+		}
+		switch kind {
+		case "ext":
+			if !strings.HasPrefix(pattern, ".") {
+				pattern = "." + pattern
+			}
+			r.exts = append(r.exts, pattern)
+		case "name", "iname":
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return r, fmt.Errorf("invalid glob '%s' in '%s'", pattern, matcher)
+			}
+			if kind == "name" {
+				r.names = append(r.names, pattern)
+			} else {
+				r.inames = append(r.inames, pattern)
+			}
+		case "path":
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return r, fmt.Errorf("invalid regular expression '%s': %s", pattern, err)
+			}
+			r.paths = append(r.paths, re)
+		case "mime":
+			if !strings.Contains(pattern, "/") {
+				return r, fmt.Errorf("invalid MIME type '%s' in '%s'", pattern, matcher)
+			}
+			r.mimes = append(r.mimes, pattern)
+		default:
+			return r, fmt.Errorf("unknown matcher '%s'", matcher)
+		}
+	}
+	for _, opt := range fields[2:] {
+		switch opt {
+		case "term":
+			r.useTerm = true
+		case "wait":
+			r.wait = true
+		default:
+			return r, fmt.Errorf("unknown option '%s'", opt)
+		}
+	}
+	alts, err := splitAlternatives(value)
+	if err != nil {
+		return r, err
+	}
+	for _, alt := range alts {
+		cmd, err := SplitFields(alt)
+		if err != nil {
+			return r, err
+		}
+		if len(cmd) == 0 {
+			return r, fmt.Errorf("missing program in '%s'", value)
+		}
+		r.cmds = append(r.cmds, cmd)
+	}
+	return r, nil
+}
+
+// splitAlternatives splits the fallback commands of a rule separated by '|', honouring quotes
+func splitAlternatives(str string) ([]string, error) {
+	var alts []string
+	var quote rune
+	start := 0
+	for i, c := range str {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '|':
+			alts = append(alts, str[start:i])
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in '%s'", str)
+	}
+	return append(alts, str[start:]), nil
+}
+
+// openers returns the openers of the programs of the rule that are installed,
+// and the programs that were tried
+func (r rule) openers(mime string) ([]Opener, []string) {
+	var openers []Opener
+	var tried []string
+	for _, cmd := range r.cmds {
+		tried = append(tried, cmd[0])
+		if _, err := exec.LookPath(cmd[0]); err != nil {
+			continue
+		}
+		useTerm := r.useTerm
+		if r.autoTerm {
+			useTerm = needsTerm(cmd[0])
+		}
+		opener := Opener{
+			Name:    cmd[0],
+			Args:    append([]string{}, cmd[1:]...),
+			UseTerm: useTerm,
+			Wait:    r.wait,
+			MIME:    mime,
+			Rule:    r.matched,
+			Source:  r.source,
+		}
+		if r.line > 0 {
+			opener.Source = fmt.Sprintf("%s:%d", r.source, r.line)
+		}
+		openers = append(openers, opener)
+	}
+	return openers, tried
+}
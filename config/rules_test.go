@@ -0,0 +1,117 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package config
+
+import "testing"
+
+func TestMatchRules(t *testing.T) {
+	var rules []rule
+	for _, line := range [][2]string{
+		{"open ext:.gz", "gz"},
+		{"open ext:.tar.gz", "targz"},
+		{"open name:backup-*.tar.gz", "backup"},
+		{"open iname:readme*", "readme"},
+		{"open path:^/srv/", "srv"},
+		{"open mime:application/*", "application"},
+		{"open mime:application/gzip", "gzip"},
+	} {
+		r, err := parseRule(line[0], line[1])
+		if err != nil {
+			t.Fatalf("parseRule(%q, %q): %s", line[0], line[1], err)
+		}
+		rules = append(rules, r)
+	}
+	// a text rule like the built-in ones
+	rules = append(rules, rule{
+		exts:  []string{".txt", ".log"},
+		names: []string{"*.log.[0-9]*"},
+		mimes: []string{"text/*"},
+		cmds:  [][]string{{"text"}},
+		text:  true,
+	})
+	tests := []struct {
+		file    string
+		mime    string
+		cmd     string
+		matched string
+	}{
+		// glob > path > ext
+		{"/srv/backup-1.tar.gz", "application/gzip", "backup", "name:backup-*.tar.gz"},
+		{"/tmp/README.md", "text/plain", "readme", "iname:readme*"},
+		{"/srv/a.tar.gz", "application/gzip", "srv", "path:^/srv/"},
+		{"/tmp/a.tar.gz", "application/gzip", "targz", "ext:.tar.gz"},
+		// the longest extension first
+		{"/tmp/a.gz", "application/gzip", "gz", "ext:.gz"},
+		{"/tmp/A.TAR.GZ", "application/gzip", "targz", "ext:.tar.gz"},
+		// exact MIME > wildcard MIME
+		{"/tmp/a.bin", "application/gzip", "gzip", "mime:application/gzip"},
+		{"/tmp/a.bin", "application/zip", "application", "mime:application/*"},
+		{"/tmp/a.c", "text/x-c", "text", "mime:text/*"},
+		// binary content against text rules
+		{"/tmp/a.txt", "text/plain", "text", "ext:.txt"},
+		{"/tmp/a.txt", "image/png", "", ""},
+		{"/tmp/app.log.2", "text/plain", "text", "name:*.log.[0-9]*"},
+		{"/tmp/app.log.2.gz", "application/gzip", "gz", "ext:.gz"},
+		{"/tmp/app.log", "application/vnd.sqlite3", "application", "mime:application/*"},
+		// no match
+		{"/tmp/a.bin", "image/png", "", ""},
+	}
+	for _, tt := range tests {
+		matched := matchRules(rules, tt.file, tt.mime)
+		cmd, matcher := "", ""
+		if len(matched) > 0 {
+			cmd, matcher = matched[0].cmds[0][0], matched[0].matched
+		}
+		if cmd != tt.cmd || matcher != tt.matched {
+			t.Errorf("matchRules(%q, %q) = %q %q, want %q %q", tt.file, tt.mime, cmd, matcher, tt.cmd, tt.matched)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		cmds  [][]string
+		err   bool
+	}{
+		{"open ext:txt", "vim", [][]string{{"vim"}}, false},
+		{"open ext:.md term wait", "vim -p | vi", [][]string{{"vim", "-p"}, {"vi"}}, false},
+		{"open mime:text/*", "sh -c 'a | b'", [][]string{{"sh", "-c", "a | b"}}, false},
+		{"open path:^/a,b/", "x", [][]string{{"x"}}, false},
+		{"open", "vim", nil, true},
+		{"open ext:", "vim", nil, true},
+		{"open ext:.a,", "vim", nil, true},
+		{"open name:[", "vim", nil, true},
+		{"open path:(", "vim", nil, true},
+		{"open mime:text", "vim", nil, true},
+		{"open size:1", "vim", nil, true},
+		{"open ext:.a fast", "vim", nil, true},
+		{"open ext:.a", "vim |", nil, true},
+		{"open ext:.a", "'vim", nil, true},
+	}
+	for _, tt := range tests {
+		r, err := parseRule(tt.key, tt.value)
+		if (err != nil) != tt.err {
+			t.Errorf("parseRule(%q, %q) error = %v, want error %v", tt.key, tt.value, err, tt.err)
+			continue
+		}
+		if err == nil && !equalCmds(r.cmds, tt.cmds) {
+			t.Errorf("parseRule(%q, %q) cmds = %q, want %q", tt.key, tt.value, r.cmds, tt.cmds)
+		}
+	}
+}
+
+// equalCmds reports whether the commands {a} and {b} are the same
+func equalCmds(a [][]string, b [][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equalFields(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
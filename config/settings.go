@@ -14,57 +14,100 @@ import (
 const ProgName = "sf"
 
 var (
-	SFLog    = fmt.Sprintf("%s/%s-sf.log", tmpDir, userName)
-	tmpDir   = os.TempDir()
-	userName = getUserName()
-	Term     = "xterm"
-	TermArgs = []string{"-e"}
+	ConfigFile   = configFile()
+	Editor       = editorCmd()
+	EditorServer = false
+	Mimeapps     = "fallback"
+	MuxTarget    = "window"
+	NvimSocket   = ""
+	Pager        = pagerCmd()
+	SFLog        = fmt.Sprintf("%s/%s-sf.log", tmpDir, userName)
+	ServerName   = ProgName
+	tmpDir       = os.TempDir()
+	userName     = getUserName()
+	Term         = terminal()
+	TermArgs     = terminalArgs(Term)
+	TermMode     = "auto"
 )
 
-// ProgExt returns the program associated by their extension
-func ProgExt(file string) (map[string]interface{}, error) {
-	var prg string
-	var prgArgs []string
-	var useTerm bool
-	prgOpts := make(map[string]interface{})
-	ext := filepath.Ext(file)
-	switch ext {
-	case ".pdf":
-		prg = "mupdf"
-		prgArgs = append(prgArgs, "-A", "8")
-	case ".aif", ".avi", ".cda", ".mid", ".midi", ".mkv", ".mov", ".mp3", ".mp4", ".mpg", ".mpeg", ".ogg", ".wav",
-		".wma", ".wmv":
-		prg = "gorum"
-		prgArgs = []string{}
-	case ".doc", ".docx", ".odt", ".ppt", ".pptx", ".rtf", ".xls", ".xlsx":
-		prg = "soffice"
-	case ".txt", ".c", ".conf", ".cpp", ".css", ".go", ".h", ".htm", ".html", ".ini", ".js", ".json", ".log", ".md",
-		".php", ".pl", ".py", ".rb", ".sh", ".sql", ".tmp", ".yaml", ".yml", ".vim", ".xhtml", ".xml":
-		// prg = "gvim"
-		// prgArgs = append(prgArgs, "--servername", ProgName, "--remote-silent")
-		prg = "vim"
-		prgArgs = []string{}
-		useTerm = true
-	case ".bmp", ".gif", ".ico", ".jpg", ".jpeg", ".png", ".svg", ".tif", ".tiff":
-		prg = "geeqie"
-		prgArgs = []string{}
-	default:
-		fi, err := os.Lstat(file)
-		if os.IsNotExist(err) {
-			return prgOpts, fmt.Errorf("progExt: error: '%s' no such file or directory\n", file)
-		} else if err != nil {
-			return prgOpts, err
-		}
-		if !fi.IsDir() && fi.Mode().IsRegular() {
-			prg = "vim"
-			prgArgs = []string{}
-			useTerm = true
+// builtinRules is the default opener table, used when no user rule matches
+var builtinRules = []rule{
+	{
+		exts:   []string{".pdf"},
+		mimes:  []string{"application/pdf"},
+		cmds:   [][]string{{"mupdf", "-A", "8"}, {"zathura"}, {"evince"}, {"okular"}},
+		source: "builtin",
+	},
+	{
+		exts: []string{".aif", ".avi", ".cda", ".mid", ".midi", ".mkv", ".mov", ".mp3", ".mp4", ".mpg", ".mpeg", ".ogg",
+			".wav", ".wma", ".wmv"},
+		mimes:  []string{"application/ogg", "audio/*", "video/*"},
+		cmds:   [][]string{{"gorum"}, {"mpv", "%F"}, {"vlc", "%F"}},
+		source: "builtin",
+	},
+	{
+		exts:   []string{".doc", ".docx", ".odt", ".ppt", ".pptx", ".rtf", ".xls", ".xlsx"},
+		cmds:   [][]string{{"soffice"}, {"libreoffice"}},
+		source: "builtin",
+	},
+	{
+		exts:     []string{".log"},
+		names:    []string{"*.log.[0-9]*"},
+		cmds:     [][]string{multiFiles(Pager)},
+		autoTerm: true,
+		text:     true,
+		source:   "builtin",
+	},
+	{
+		exts: []string{".txt", ".c", ".conf", ".cpp", ".css", ".go", ".h", ".htm", ".html", ".ini", ".js", ".json", ".md",
+			".php", ".pl", ".py", ".rb", ".sh", ".sql", ".tmp", ".yaml", ".yml", ".vim", ".xhtml", ".xml"},
+		names:    []string{"Dockerfile", "Makefile", "README"},
+		mimes:    []string{"text/*"},
+		cmds:     [][]string{multiFiles(Editor), multiFiles([]string{"vi"}), multiFiles([]string{"nano"})},
+		autoTerm: true,
+		text:     true,
+		source:   "builtin",
+	},
+	{
+		exts:   []string{".bmp", ".gif", ".ico", ".jpg", ".jpeg", ".png", ".svg", ".tif", ".tiff"},
+		mimes:  []string{"image/*"},
+		cmds:   [][]string{{"geeqie", "%F"}, {"feh", "%F"}, {"sxiv", "%F"}, {"eog", "%F"}},
+		source: "builtin",
+	},
+}
+
+// CurrentTermMode returns how the programs that need a terminal are run: "window"
+// opens a new Term window, "inline" runs them in the current terminal and "tmux"
+// or "screen" open them inside the terminal multiplexer. The "auto" mode chooses
+// the multiplexer sf is running in, or else inline when there is no graphical
+// display available
+func CurrentTermMode() string {
+	if TermMode != "auto" {
+		return TermMode
+	}
+	if os.Getenv("TMUX") != "" {
+		return "tmux"
+	}
+	if os.Getenv("STY") != "" {
+		return "screen"
+	}
+	if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		return "inline"
+	}
+	return "window"
+}
+
+// configFile returns the default path of the user configuration file
+func configFile() string {
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return ""
 		}
+		configDir = filepath.Join(homeDir, ".config")
 	}
-	prgOpts["name"] = prg
-	prgOpts["args"] = prgArgs
-	prgOpts["useTerm"] = useTerm
-	return prgOpts, nil
+	return filepath.Join(configDir, ProgName, ProgName+".conf")
 }
 
 // getUserName returns the current user
@@ -22,3 +22,13 @@ func Move(line int, col int) {
 func ResetModes() {
 	fmt.Printf("%s[0m", Escape)
 }
+
+// Hide makes the cursor invisible
+func Hide() {
+	fmt.Printf("%s[?25l", Escape)
+}
+
+// Show makes the cursor visible
+func Show() {
+	fmt.Printf("%s[?25h", Escape)
+}
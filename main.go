@@ -4,9 +4,12 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"strings"
 )
 
 // local packages
@@ -16,35 +19,185 @@ import (
 	"github.com/gonzaru/sf/utils"
 )
 
+// version is the sf version, set at build time with -ldflags "-X main.version=..."
+var version = "devel"
+
 // help shows help information
-func help() {
+func help(flags *flag.FlagSet) {
 	fmt.Print("Usage:\n")
-	fmt.Printf("  %s                # opens an interactive menu\n", config.ProgName)
-	fmt.Printf("  %s /path/to/file  # opens the local file\n", config.ProgName)
+	fmt.Printf("  %s [flags]                              # opens an interactive menu\n", config.ProgName)
+	fmt.Printf("  %s [flags] /path/to/dir                 # opens an interactive menu in the directory\n", config.ProgName)
+	fmt.Printf("  %s [flags] /path/to/file...             # opens the local files\n", config.ProgName)
+	fmt.Printf("  %s [flags] open /path/to/file...        # opens the local files\n", config.ProgName)
+	fmt.Printf("  %s [flags] explain /path/to/file...     # shows which program opens the files\n", config.ProgName)
+	fmt.Printf("  %s [flags] list [/path/to/dir]          # lists the directory and the program of each file\n", config.ProgName)
+	fmt.Printf("  %s --choose [/path/to/dir]              # prints the chosen files instead of opening them\n", config.ProgName)
+	fmt.Print("Flags:\n")
+	flags.SetOutput(os.Stdout)
+	flags.PrintDefaults()
+	fmt.Printf("Configuration file:\n  %s\n", config.ConfigFile)
+}
+
+// usageError shows a command line error and exits
+func usageError(err error) {
+	utils.ErrPrintf("%s: error: %s\n", config.ProgName, err)
+	utils.ErrPrintf("Run '%s --help' for usage.\n", config.ProgName)
+	os.Exit(2)
+}
+
+// sortFlag defines the flag --sort in {flags}
+func sortFlag(flags *flag.FlagSet, sortOrder *string) {
+	usage := fmt.Sprintf("sorts the files by `order`: %s (default %s)", strings.Join(sf.SortOrders, ", "), *sortOrder)
+	flags.Func("sort", usage, func(value string) error {
+		for _, order := range sf.SortOrders {
+			if value == order {
+				*sortOrder = value
+				return nil
+			}
+		}
+		return fmt.Errorf("expected %s", strings.Join(sf.SortOrders, ", "))
+	})
 }
 
 // main sf
 func main() {
+	opts := sf.DefaultOptions()
+	flags := flag.NewFlagSet(config.ProgName, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	configFile := flags.String("config", config.ConfigFile, "reads the configuration from `file`")
+	logFile := flags.String("log", "", "writes the log to `file` (default "+config.SFLog+")")
+	showVersion := flags.Bool("version", false, "shows the version")
+	showHelp := flags.Bool("help", false, "shows help information")
+	flags.BoolVar(showHelp, "h", false, "shows help information")
+	explain := flags.Bool("explain", false, "same as the explain command")
+	flags.StringVar(&opts.StartDir, "start-dir", "", "opens the interactive menu in `dir`")
+	flags.BoolVar(&opts.Hidden, "hidden", opts.Hidden, "shows the files starting with '.', use --hidden=false to hide them")
+	noHidden := flags.Bool("no-hidden", false, "same as --hidden=false")
+	sortFlag(flags, &opts.Sort)
+	flags.BoolVar(&opts.Choose, "choose", false, "prints the chosen files to the standard output instead of opening them")
+	flags.StringVar(&opts.ChooseFile, "choose-file", "", "writes the chosen files to `file` instead of opening them")
+	flags.BoolVar(&opts.Print0, "print0", false, "separates the chosen files with NUL instead of newline")
+	flags.StringVar(&opts.LastDir, "last-dir", "", "writes the current directory to `file` when exiting with q")
+	if errFp := flags.Parse(os.Args[1:]); errFp != nil {
+		usageError(errFp)
+	}
+	config.ConfigFile = *configFile
+	if *noHidden {
+		opts.Hidden = false
+	}
+	if *showHelp {
+		help(flags)
+		return
+	}
+	if *showVersion {
+		fmt.Printf("%s %s\n", config.ProgName, version)
+		return
+	}
+	if errLc := config.Load(config.ConfigFile); errLc != nil {
+		utils.ErrPrint(errLc)
+		os.Exit(1)
+	}
+	if *logFile != "" {
+		config.SFLog = *logFile
+	}
 	if errSl := sf.SetLog(); errSl != nil {
 		utils.ErrPrint(errSl)
 		log.Fatal(errSl)
 	}
-	args := os.Args[1:]
-	lenArgs := len(args)
-	if lenArgs > 0 {
-		if lenArgs == 1 {
-			file := args[0]
-			if errSp := sf.Spawn(file); errSp != nil {
+	args := flags.Args()
+	command := ""
+	if *explain {
+		command = "explain"
+	} else if len(args) > 0 {
+		switch args[0] {
+		case "open", "explain", "list":
+			command = args[0]
+			args = args[1:]
+		}
+	}
+	if command != "" {
+		cmdFlags := flag.NewFlagSet(config.ProgName+" "+command, flag.ContinueOnError)
+		cmdFlags.SetOutput(ioutil.Discard)
+		cmdHelp := cmdFlags.Bool("help", false, "shows help information")
+		cmdFlags.BoolVar(cmdHelp, "h", false, "shows help information")
+		if command == "list" {
+			cmdFlags.BoolVar(&opts.Hidden, "hidden", opts.Hidden, "shows the files starting with '.', use --hidden=false to hide them")
+			cmdFlags.BoolVar(noHidden, "no-hidden", *noHidden, "same as --hidden=false")
+			sortFlag(cmdFlags, &opts.Sort)
+		}
+		if errFp := cmdFlags.Parse(args); errFp != nil {
+			usageError(fmt.Errorf("%s: %s", command, errFp))
+		}
+		if *cmdHelp {
+			help(flags)
+			return
+		}
+		args = cmdFlags.Args()
+		if *noHidden {
+			opts.Hidden = false
+		}
+	}
+	switch command {
+	case "open":
+		if len(args) == 0 {
+			usageError(fmt.Errorf("open: missing file operand"))
+		}
+		if errSp := sf.SpawnFiles(args); errSp != nil {
+			utils.ErrPrint(errSp)
+			log.Fatal(errSp)
+		}
+	case "explain":
+		if len(args) == 0 {
+			usageError(fmt.Errorf("explain: missing file operand"))
+		}
+		failed := false
+		for num, file := range args {
+			if num > 0 {
+				fmt.Print("\n")
+			}
+			explanation, errEx := sf.Explain(file)
+			fmt.Print(explanation)
+			if errEx != nil {
+				utils.ErrPrintf("%s\n", strings.TrimRight(errEx.Error(), "\n"))
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+	case "list":
+		if len(args) > 1 {
+			usageError(fmt.Errorf("list: too many arguments"))
+		}
+		dir := "."
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		list, errLi := sf.List(dir, opts)
+		if errLi != nil {
+			utils.ErrPrint(errLi)
+			os.Exit(1)
+		}
+		fmt.Print(list)
+	default:
+		if len(args) == 1 {
+			if fi, errOs := os.Stat(args[0]); errOs == nil && fi.IsDir() {
+				opts.StartDir = args[0]
+				args = nil
+			}
+		}
+		if len(args) > 0 && (opts.Choose || opts.ChooseFile != "") {
+			usageError(fmt.Errorf("--choose only takes a directory"))
+		}
+		if len(args) > 0 {
+			if errSp := sf.SpawnFiles(args); errSp != nil {
 				utils.ErrPrint(errSp)
 				log.Fatal(errSp)
 			}
-		} else {
-			help()
-			os.Exit(1)
+			return
 		}
-	} else {
 		go sf.SignalHandler()
-		if errSf := sf.Run(); errSf != nil {
+		if errSf := sf.Run(opts); errSf != nil {
 			utils.ErrPrint(errSf)
 			log.Fatal(errSf)
 		}
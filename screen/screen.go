@@ -5,17 +5,20 @@ package screen
 
 import (
 	"errors"
+	"fmt"
 	"os"
-	"os/exec"
-	"strconv"
-	"strings"
+)
+
+// local packages
+import (
+	"github.com/gonzaru/sf/cursor"
+	"github.com/gonzaru/sf/term"
 )
 
 // Clear clears entire terminal screen
 func Clear() error {
-	cmd := exec.Command("clear")
-	cmd.Stdout = os.Stdout
-	if err := cmd.Run(); err != nil {
+	// moves the cursor home and clears the screen
+	if _, err := fmt.Printf("%s[H%s[2J", cursor.Escape, cursor.Escape); err != nil {
 		return err
 	}
 	return nil
@@ -23,26 +26,22 @@ func Clear() error {
 
 // Size obtains the terminal number of rows and columns
 func Size() ([]int, error) {
-	size := make([]int, 2)
-	cmd := exec.Command("stty", "size")
-	cmd.Stdin = os.Stdin
-	content, err := cmd.Output()
+	rows, cols, err := term.GetSize(int(os.Stdin.Fd()))
 	if err != nil {
 		return nil, err
 	}
-	contentSplit := strings.Fields(string(content))
-	if len(contentSplit) == 0 {
+	if rows == 0 || cols == 0 {
 		return nil, errors.New("size: error: terminal's number of rows and columns was not found")
 	}
-	rows, errSa := strconv.Atoi(contentSplit[0])
-	if errSa != nil {
-		return nil, errSa
-	}
-	cols, errSa := strconv.Atoi(contentSplit[1])
-	if errSa != nil {
-		return nil, errSa
-	}
-	size[0] = rows
-	size[1] = cols
-	return size, nil
+	return []int{rows, cols}, nil
+}
+
+// EnterAltScreen switches to the alternate screen buffer, keeping the user's screen and scrollback
+func EnterAltScreen() {
+	fmt.Printf("%s[?1049h", cursor.Escape)
+}
+
+// ExitAltScreen switches back to the normal screen buffer
+func ExitAltScreen() {
+	fmt.Printf("%s[?1049l", cursor.Escape)
 }
@@ -0,0 +1,65 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package sf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// chooser reports whether sf prints the chosen files instead of opening them
+func (opts Options) chooser() bool {
+	return opts.Choose || opts.ChooseFile != ""
+}
+
+// useTTY draws the interface on /dev/tty so that the standard output only gets the
+// chosen files, it returns the original standard output and a function to restore it
+func useTTY() (*os.File, func(), error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	stdin, stdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = tty, tty
+	restore := func() {
+		os.Stdin, os.Stdout = stdin, stdout
+		tty.Close()
+	}
+	return stdout, restore, nil
+}
+
+// toggleMark marks or unmarks the file {name} of the current directory
+func (sf *selectFile) toggleMark(name string) {
+	path := filepath.Join(sf.pwd, name)
+	if sf.marked[path] {
+		delete(sf.marked, path)
+	} else {
+		sf.marked[path] = true
+	}
+}
+
+// choose writes the marked files, or else the file {name}, to the chooser output
+func (sf *selectFile) choose(name string, opts Options) error {
+	var files []string
+	for path := range sf.marked {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	if len(files) == 0 {
+		files = append(files, filepath.Join(sf.pwd, name))
+	}
+	sep := "\n"
+	if opts.Print0 {
+		sep = "\x00"
+	}
+	content := strings.Join(files, sep) + sep
+	if opts.ChooseFile != "" {
+		return ioutil.WriteFile(opts.ChooseFile, []byte(content), 0600)
+	}
+	_, err := sf.out.WriteString(content)
+	return err
+}
@@ -0,0 +1,60 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package sf
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// local packages
+import (
+	"github.com/gonzaru/sf/config"
+)
+
+// Explain describes which program opens the file and the command lines that
+// Spawn would execute, without running anything
+func Explain(file string) (string, error) {
+	var explain strings.Builder
+	file, pos := config.SplitPosition(file)
+	absFile, errAf := filepath.Abs(file)
+	if errAf != nil {
+		return "", errAf
+	}
+	explain.WriteString(fmt.Sprintf("file:    %s\n", absFile))
+	if pos.Line > 0 {
+		explain.WriteString(fmt.Sprintf("line:    %d\n", pos.Line))
+	}
+	if pos.Col > 0 {
+		explain.WriteString(fmt.Sprintf("column:  %d\n", pos.Col))
+	}
+	mime, errDm := config.DetectMIME(file)
+	if errDm != nil {
+		return explain.String(), errDm
+	}
+	explain.WriteString(fmt.Sprintf("mime:    %s\n", mime))
+	opener, errPe := config.ProgExt(file)
+	if errPe != nil {
+		return explain.String(), errPe
+	}
+	rule := opener.Rule
+	if rule == "" {
+		rule = "-"
+	}
+	explain.WriteString(fmt.Sprintf("rule:    %s\n", rule))
+	explain.WriteString(fmt.Sprintf("source:  %s\n", opener.Source))
+	mode, cmdLines, errCl := commandLines([]string{file}, pos, opener)
+	if errCl != nil {
+		return explain.String(), errCl
+	}
+	if opener.Wait && mode != "tmux" && mode != "screen" {
+		mode += " (wait)"
+	}
+	explain.WriteString(fmt.Sprintf("mode:    %s\n", mode))
+	for _, cmdLine := range cmdLines {
+		explain.WriteString(fmt.Sprintf("command: %s\n", config.JoinFields(cmdLine)))
+	}
+	return explain.String(), nil
+}
@@ -0,0 +1,184 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package sf
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// local packages
+import (
+	"github.com/gonzaru/sf/config"
+	"github.com/gonzaru/sf/screen"
+)
+
+// stderrLen is the maximum number of bytes kept from the standard error of a job
+const stderrLen = 4096
+
+// job data type
+type job struct {
+	pid     int
+	cmdLine string
+	start   time.Time
+	end     time.Time
+	running bool
+	status  string
+	stderr  string
+}
+
+// jobs are the programs spawned in the background
+var jobs struct {
+	sync.Mutex
+	list []*job
+}
+
+// signals are the signals that can be sent to a job
+var signals = map[string]syscall.Signal{
+	"CONT": syscall.SIGCONT,
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"KILL": syscall.SIGKILL,
+	"STOP": syscall.SIGSTOP,
+	"TERM": syscall.SIGTERM,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+}
+
+// startJob starts the command in the background and reaps it when it finishes,
+// the returned channel is closed at that moment
+func startJob(cmd *exec.Cmd) (<-chan struct{}, error) {
+	// the standard error goes to an unlinked file instead of a pipe,
+	// so the program does not receive SIGPIPE if sf exits first
+	errFile, errTf := ioutil.TempFile("", config.ProgName+"-job-*.err")
+	if errTf != nil {
+		return nil, errTf
+	}
+	cmd.Stderr = errFile
+	if errCs := cmd.Start(); errCs != nil {
+		errFile.Close()
+		os.Remove(errFile.Name())
+		return nil, errCs
+	}
+	os.Remove(errFile.Name())
+	j := &job{
+		pid:     cmd.Process.Pid,
+		cmdLine: config.JoinFields(cmd.Args),
+		start:   time.Now(),
+		running: true,
+	}
+	jobs.Lock()
+	jobs.list = append(jobs.list, j)
+	jobs.Unlock()
+	log.Printf("startJob: info: pid %d started '%s'\n", j.pid, j.cmdLine)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		errWa := cmd.Wait()
+		status := "exit 0"
+		if errWa != nil {
+			status = errWa.Error()
+		}
+		stderr := tail(errFile, stderrLen)
+		errFile.Close()
+		jobs.Lock()
+		j.running = false
+		j.end = time.Now()
+		j.status = status
+		j.stderr = stderr
+		jobs.Unlock()
+		log.Printf("startJob: info: pid %d '%s' finished with '%s' after %s, stderr: %q\n", j.pid, j.cmdLine, status,
+			j.end.Sub(j.start).Round(time.Millisecond), stderr)
+	}()
+	return done, nil
+}
+
+// tail returns the last {size} bytes of the file
+func tail(file *os.File, size int64) string {
+	fi, err := file.Stat()
+	if err != nil {
+		return ""
+	}
+	offset := fi.Size() - size
+	if offset < 0 {
+		offset = 0
+	}
+	content, err := ioutil.ReadAll(io.NewSectionReader(file, offset, size))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// showJobs shows the spawned programs and sends a signal to the chosen one
+func showJobs() error {
+	if errSc := screen.Clear(); errSc != nil {
+		return errSc
+	}
+	fmt.Print("# jobs\n")
+	jobs.Lock()
+	list := make([]job, len(jobs.list))
+	for num, j := range jobs.list {
+		list[num] = *j
+	}
+	jobs.Unlock()
+	if len(list) == 0 {
+		fmt.Print("no programs were spawned\n")
+	}
+	for num, j := range list {
+		if j.running {
+			fmt.Printf("%d) [running] pid %d since %s: %s\n", num+1, j.pid, j.start.Format("15:04:05"), j.cmdLine)
+			continue
+		}
+		fmt.Printf("%d) [%s] pid %d %s-%s: %s\n", num+1, j.status, j.pid, j.start.Format("15:04:05"),
+			j.end.Format("15:04:05"), j.cmdLine)
+		if j.stderr != "" {
+			lines := strings.Split(j.stderr, "\n")
+			fmt.Printf("   stderr: %s\n", lines[len(lines)-1])
+		}
+	}
+	fmt.Print("\nSend a signal to a job (e.g. '1 TERM') or press ENTER to go back: ")
+	answer, errRl := readLine()
+	if errRl != nil {
+		return errRl
+	}
+	fields := strings.Fields(answer)
+	if len(fields) == 0 {
+		return nil
+	}
+	if len(fields) != 2 {
+		return fmt.Errorf("showJobs: error: '%s' is not valid, expected a job number and a signal", answer)
+	}
+	num, errSa := strconv.Atoi(fields[0])
+	if errSa != nil || num < 1 || num > len(list) {
+		return fmt.Errorf("showJobs: error: job '%s' does not exist", fields[0])
+	}
+	if !list[num-1].running {
+		return fmt.Errorf("showJobs: error: job %d is not running", num)
+	}
+	sigName := strings.TrimPrefix(strings.ToUpper(fields[1]), "SIG")
+	sig, found := signals[sigName]
+	if !found {
+		sigNum, errSa := strconv.Atoi(sigName)
+		if errSa != nil {
+			return fmt.Errorf("showJobs: error: signal '%s' is not supported", fields[1])
+		}
+		sig = syscall.Signal(sigNum)
+	}
+	// the program runs in its own process group, the signal goes to the whole group
+	if errSk := syscall.Kill(-list[num-1].pid, sig); errSk != nil {
+		return errSk
+	}
+	log.Printf("showJobs: info: sent signal '%s' to pid %d\n", sig, list[num-1].pid)
+	return nil
+}
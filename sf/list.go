@@ -0,0 +1,112 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package sf
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// local packages
+import (
+	"github.com/gonzaru/sf/config"
+	"github.com/gonzaru/sf/utils"
+)
+
+// Options data type
+type Options struct {
+	StartDir   string
+	Hidden     bool
+	Sort       string
+	Choose     bool
+	ChooseFile string
+	Print0     bool
+	LastDir    string
+}
+
+// SortOrders are the valid values of Options.Sort
+var SortOrders = []string{"name", "size", "time", "ext"}
+
+// DefaultOptions returns the options used when none are given
+func DefaultOptions() Options {
+	return Options{
+		Hidden: true,
+		Sort:   "name",
+	}
+}
+
+// readDir reads the directory {dir} filtering and sorting its entries as set in {opts}
+func readDir(dir string, opts Options) ([]fs.FileInfo, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.Hidden {
+		var visible []fs.FileInfo
+		for _, file := range files {
+			if !strings.HasPrefix(file.Name(), ".") {
+				visible = append(visible, file)
+			}
+		}
+		files = visible
+	}
+	// ioutil.ReadDir sorts by name, the other orders keep it for equal entries
+	switch opts.Sort {
+	case "", "name":
+	case "size":
+		sort.SliceStable(files, func(i, j int) bool {
+			return files[i].Size() > files[j].Size()
+		})
+	case "time":
+		sort.SliceStable(files, func(i, j int) bool {
+			return files[i].ModTime().After(files[j].ModTime())
+		})
+	case "ext":
+		sort.SliceStable(files, func(i, j int) bool {
+			return filepath.Ext(files[i].Name()) < filepath.Ext(files[j].Name())
+		})
+	default:
+		return nil, fmt.Errorf("readDir: error: unknown sort order '%s'\n", opts.Sort)
+	}
+	return files, nil
+}
+
+// List describes the entries of the directory {dir} and the program that opens each of them
+func List(dir string, opts Options) (string, error) {
+	files, errRd := readDir(dir, opts)
+	if errRd != nil {
+		return "", errRd
+	}
+	var list strings.Builder
+	tw := tabwriter.NewWriter(&list, 0, 8, 2, ' ', 0)
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name())
+		symbol, errFi := utils.FileIndicator(path)
+		if errFi != nil {
+			return "", errFi
+		}
+		program := "-"
+		if fi, errOs := os.Stat(path); errOs == nil && fi.IsDir() {
+			program = "(directory)"
+		} else if opener, errPe := config.ProgExt(path); errPe == nil {
+			program = config.JoinFields(append([]string{opener.Name}, opener.Args...))
+		} else if errNi := (*config.NotInstalledError)(nil); errors.As(errPe, &errNi) {
+			program = "(not installed)"
+		} else if errors.Is(errPe, config.ErrNoOpener) {
+			program = "(no opener)"
+		}
+		fmt.Fprintf(tw, "%s%s\t%s\n", file.Name(), symbol, program)
+	}
+	if errFl := tw.Flush(); errFl != nil {
+		return "", errFl
+	}
+	return list.String(), nil
+}
@@ -0,0 +1,64 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package sf
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// local packages
+import (
+	"github.com/gonzaru/sf/config"
+)
+
+// muxCommands returns the command lines that open the program in a new window or
+// pane of the terminal multiplexer {mux}
+func muxCommands(mux string, prgAndParams []string) ([][]string, error) {
+	pwd, errOg := os.Getwd()
+	if errOg != nil {
+		return nil, errOg
+	}
+	var cmds [][]string
+	switch mux {
+	case "tmux":
+		var tmuxArgs []string
+		switch config.MuxTarget {
+		case "hsplit":
+			tmuxArgs = []string{"split-window", "-h"}
+		case "vsplit":
+			tmuxArgs = []string{"split-window", "-v"}
+		default:
+			tmuxArgs = []string{"new-window"}
+		}
+		tmuxArgs = append(tmuxArgs, "-c", pwd)
+		cmds = append(cmds, append(append([]string{"tmux"}, tmuxArgs...), prgAndParams...))
+	case "screen":
+		screenCmd := func(args ...string) []string {
+			return append([]string{"screen", "-S", os.Getenv("STY"), "-X"}, args...)
+		}
+		switch config.MuxTarget {
+		case "hsplit":
+			cmds = append(cmds, screenCmd("split", "-v"), screenCmd("focus"))
+		case "vsplit":
+			cmds = append(cmds, screenCmd("split"), screenCmd("focus"))
+		}
+		cmds = append(cmds, screenCmd("chdir", pwd))
+		cmds = append(cmds, screenCmd(append([]string{"screen"}, prgAndParams...)...))
+	default:
+		return nil, fmt.Errorf("muxCommands: error: terminal multiplexer '%s' is not supported", mux)
+	}
+	return cmds, nil
+}
+
+// runMux runs the command lines of the terminal multiplexer
+func runMux(cmds [][]string) error {
+	for _, args := range cmds {
+		if out, errCo := exec.Command(args[0], args[1:]...).CombinedOutput(); errCo != nil {
+			return fmt.Errorf("runMux: error: '%s' %s %s", args[0], errCo, out)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package sf
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// local packages
+import (
+	"github.com/gonzaru/sf/config"
+)
+
+// editorServer returns the opener that sends the file to an editor server,
+// a new editor instance that acts as a server is used when none is reachable
+func editorServer(opener config.Opener) config.Opener {
+	prg := opener.Name
+	var serverArgs []string
+	remote := false
+	tabs := false
+	for _, arg := range opener.Args {
+		if arg == "-p" {
+			tabs = true
+		}
+	}
+	switch filepath.Base(prg) {
+	case "vim", "gvim":
+		if !vimClientServer(prg) {
+			return opener
+		}
+		serverName := strings.ToUpper(config.ServerName)
+		serverArgs = []string{"--servername", serverName}
+		if vimServerRunning(prg, serverName) {
+			remote = true
+			if tabs {
+				serverArgs = append(serverArgs, "--remote-tab-silent")
+			} else {
+				serverArgs = append(serverArgs, "--remote-silent")
+			}
+		}
+	case "nvim":
+		socket := os.Getenv("NVIM")
+		if socket == "" {
+			socket = config.NvimSocket
+		}
+		if socket == "" {
+			return opener
+		}
+		if exec.Command(prg, "--server", socket, "--remote-expr", "1").Run() == nil {
+			remote = true
+			if tabs {
+				serverArgs = []string{"--server", socket, "--remote-tab"}
+			} else {
+				serverArgs = []string{"--server", socket, "--remote"}
+			}
+		} else if _, err := os.Stat(socket); os.IsNotExist(err) {
+			serverArgs = []string{"--listen", socket}
+		}
+	case "emacs", "emacsclient":
+		if exec.Command("emacsclient", "--eval", "t").Run() == nil {
+			prg = "emacsclient"
+			serverArgs = []string{"--no-wait"}
+			remote = true
+			break
+		}
+		// without a server emacsclient fails, a new emacs is started as the server instead
+		if _, err := exec.LookPath("emacs"); err != nil {
+			return opener
+		}
+		if filepath.Base(prg) == "emacsclient" {
+			opener.Args = emacsArgs(opener.Args)
+		}
+		prg = "emacs"
+		serverArgs = []string{"--funcall", "server-start"}
+	default:
+		return opener
+	}
+	args := opener.Args
+	if remote {
+		// the remote commands only take files, the other options of the rule are dropped
+		args = nil
+		for _, arg := range opener.Args {
+			// neovim does not take a position with --remote
+			if prg == "nvim" && (strings.Contains(arg, "%l") || strings.Contains(arg, "%c")) {
+				continue
+			}
+			if strings.Contains(arg, "%") {
+				args = append(args, arg)
+			}
+		}
+		opener.UseTerm = false
+	}
+	opener.Name = prg
+	opener.Args = append(serverArgs, args...)
+	return opener
+}
+
+// emacsArgs returns the emacs arguments of the emacsclient arguments {args}
+func emacsArgs(args []string) []string {
+	var newArgs []string
+	for _, arg := range args {
+		switch arg {
+		case "-t", "-nw", "--tty":
+			newArgs = append(newArgs, "-nw")
+		case "-c", "--create-frame", "-n", "--no-wait", "-q", "--quiet":
+		default:
+			newArgs = append(newArgs, arg)
+		}
+	}
+	return newArgs
+}
+
+// vimClientServer reports whether vim was compiled with the clientserver feature
+func vimClientServer(prg string) bool {
+	out, err := exec.Command(prg, "--version").Output()
+	return err == nil && strings.Contains(string(out), "+clientserver")
+}
+
+// vimServerRunning reports whether the vim server {serverName} is running
+func vimServerRunning(prg string, serverName string) bool {
+	out, err := exec.Command(prg, "--serverlist").Output()
+	if err != nil {
+		return false
+	}
+	for _, name := range strings.Fields(string(out)) {
+		if name == serverName {
+			return true
+		}
+	}
+	return false
+}
@@ -14,9 +14,10 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
-	"runtime"
+	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 )
 
@@ -25,12 +26,15 @@ import (
 	"github.com/gonzaru/sf/config"
 	"github.com/gonzaru/sf/cursor"
 	"github.com/gonzaru/sf/screen"
+	"github.com/gonzaru/sf/term"
 	"github.com/gonzaru/sf/utils"
 )
 
 // selectFile data type
 type selectFile struct {
 	files       []fs.FileInfo
+	marked      map[string]bool
+	out         *os.File
 	padStr      string
 	pwd         string
 	oldPwd      string
@@ -43,23 +47,15 @@ type selectFile struct {
 	pages       int
 	perPage     int
 	startOffset int
+	tooSmall    bool
 }
 
+// foregroundProg is 1 while a program runs in the foreground of the current terminal
+var foregroundProg int32
+
 // finishSF performs actions before leaving sf
 func finishSF() error {
-	fileFlag := "-f"
-	if runtime.GOOS == "linux" {
-		fileFlag = "-F"
-	}
-	if errEc := exec.Command("stty", fileFlag, "/dev/tty", "echo").Run(); errEc != nil {
-		log.Fatal(errEc)
-	}
-	cmdSs := exec.Command("stty", "sane")
-	cmdSs.Stdin = os.Stdin
-	if errCr := cmdSs.Run(); errCr != nil {
-		log.Fatal(errCr)
-	}
-	return nil
+	return restoreTerminal()
 }
 
 // helpSF shows sf' help information
@@ -70,16 +66,22 @@ func helpSF() string {
 	help.WriteString("-       # changes to parent directory\n")
 	help.WriteString("_       # changes to previous directory [^,p]\n")
 	help.WriteString("~       # changes to home user directory\n")
-	help.WriteString("h       # goes to previous page\n")
-	help.WriteString("l       # goes to next page\n")
+	help.WriteString("h       # goes to previous page [PgUp]\n")
+	help.WriteString("l       # goes to next page [PgDn]\n")
 	help.WriteString("j       # goes one line downward\n")
 	help.WriteString("k       # goes one line upward\n")
-	help.WriteString("J       # goes to bottom line\n")
-	help.WriteString("K       # goes to top line\n")
+	help.WriteString("J       # goes to bottom line [End]\n")
+	help.WriteString("K       # goes to top line [Home]\n")
 	help.WriteString("r       # redraws terminal screen\n")
 	help.WriteString("Enter   # selects the file or directory\n")
+	help.WriteString("Space   # marks the file to choose [--choose]\n")
+	help.WriteString("o       # opens the file with a chosen program\n")
+	help.WriteString("e       # explains which program opens the file\n")
+	help.WriteString("&       # shows the spawned programs [jobs]\n")
+	help.WriteString("q       # exits sf, writing the current directory [--last-dir]\n")
+	help.WriteString("^Z      # suspends sf, fg resumes it\n")
 	help.WriteString("Escape  # exits sf\n")
-	help.WriteString("?       # shows sf' help information\n")
+	help.WriteString("?       # shows sf' help information [F1]\n")
 	return help.String()
 }
 
@@ -98,19 +100,33 @@ func (sf *selectFile) drawHeader() error {
 // drawBody draws sf body
 func (sf *selectFile) drawBody(min int, max int) (int, error) {
 	lines := 0
-	for num, file := range sf.files {
+	for num := range sf.files {
 		if num >= min && num <= max {
-			symbol, err := utils.FileIndicator(file.Name())
-			if err != nil {
+			if err := sf.drawLine(num); err != nil {
 				return -1, err
 			}
-			fmt.Printf(" %"+sf.padStr+"d) %s%s\n", num+1, file.Name(), symbol)
+			fmt.Print("\n")
 			lines++
 		}
 	}
 	return lines, nil
 }
 
+// drawLine draws the line of the file number {num}, the marked files start with '+'
+func (sf *selectFile) drawLine(num int) error {
+	file := sf.files[num]
+	symbol, err := utils.FileIndicator(file.Name())
+	if err != nil {
+		return err
+	}
+	mark := " "
+	if sf.marked[filepath.Join(sf.pwd, file.Name())] {
+		mark = "+"
+	}
+	fmt.Printf("%s%"+sf.padStr+"d) %s%s", mark, num+1, file.Name(), symbol)
+	return nil
+}
+
 // drawFooter draws sf footer
 func (sf *selectFile) drawFooter(pos int) error {
 	if len(sf.files) > 0 {
@@ -179,6 +195,8 @@ func (sf *selectFile) nextPage() error {
 	if limitOffset > len(sf.files) {
 		limitOffset = len(sf.files)
 	}
+	cursor.Hide()
+	defer cursor.Show()
 	if errSc := screen.Clear(); errSc != nil {
 		return errSc
 	}
@@ -206,6 +224,8 @@ func (sf *selectFile) prevPage(curTop bool) error {
 	sf.page--
 	sf.startOffset -= sf.perPage - (sf.linesHeader + sf.linesFooter)
 	limitOffset := (sf.startOffset + sf.perPage) - (sf.linesHeader + sf.linesFooter + 1)
+	cursor.Hide()
+	defer cursor.Show()
 	if errSc := screen.Clear(); errSc != nil {
 		return errSc
 	}
@@ -228,12 +248,195 @@ func (sf *selectFile) prevPage(curTop bool) error {
 	return nil
 }
 
-// Run selects a file using keyboard interactively
-func Run() error {
+// errTooSmall is returned by layout when the listing does not fit in the terminal
+var errTooSmall = errors.New("sf: error: the terminal window is too small")
+
+// relayout draws the listing again like layout, when the terminal became too small it
+// shows a notice instead and the keys are ignored until the terminal is resized
+func (sf *selectFile) relayout(selected int) error {
+	errLa := sf.layout(selected)
+	sf.tooSmall = errLa == errTooSmall
+	if !sf.tooSmall {
+		return errLa
+	}
+	if errSc := screen.Clear(); errSc != nil {
+		return errSc
+	}
+	fmt.Print("# the terminal window is too small, resize it or press Escape to exit")
+	return nil
+}
+
+// selected returns the number of the selected file
+func (sf *selectFile) selected() int {
+	return (sf.curPos + sf.startOffset) - (sf.linesHeader + 1)
+}
+
+// fileIndex returns the number of the file {name}, or -1 when it is not listed
+func (sf *selectFile) fileIndex(name string) int {
+	for num, file := range sf.files {
+		if file.Name() == name {
+			return num
+		}
+	}
+	return -1
+}
+
+// layout computes the pages for the current terminal size and draws the page of the
+// file number {selected}, leaving it selected
+func (sf *selectFile) layout(selected int) error {
+	screenSize, errSs := screen.Size()
+	if errSs != nil {
+		return errSs
+	}
+	sf.perPage = screenSize[0]
+	if sf.perPage < sf.linesHeader+sf.linesFooter+1 {
+		return errTooSmall
+	}
+	if selected < 0 || selected >= len(sf.files) {
+		selected = 0
+	}
+	bodyLen := sf.perPage - (sf.linesHeader + sf.linesFooter)
+	sf.startOffset = (selected / bodyLen) * bodyLen
+	cursor.Hide()
+	defer cursor.Show()
+	if errSc := screen.Clear(); errSc != nil {
+		return errSc
+	}
+	if errDh := sf.drawHeader(); errDh != nil {
+		return errDh
+	}
+	var errDb error
+	sf.linesBody, errDb = sf.drawBody(sf.startOffset, sf.startOffset+bodyLen-1)
+	if errDb != nil {
+		return errDb
+	}
+	sf.page = sf.startOffset/bodyLen + 1
+	sf.pages = int(math.Ceil(float64(len(sf.files)) / float64(bodyLen)))
+	if errDf := sf.drawFooter(selected); errDf != nil {
+		return errDf
+	}
+	sf.curPos = sf.linesHeader + 1 + selected - sf.startOffset
+	cursor.ResetModes()
+	cursor.Move(sf.curPos, sf.padInt+1)
+	return nil
+}
+
+// openWith shows all the programs that can open the file and runs the chosen one
+func (sf *selectFile) openWith(file string) error {
+	candidates, errPc := config.ProgCandidates(file)
+	if errPc != nil {
+		return errPc
+	}
+	if errSc := screen.Clear(); errSc != nil {
+		return errSc
+	}
+	fmt.Printf("# open with: %s\n", file)
+	for num, opener := range candidates {
+		cmdLine := config.JoinFields(append([]string{opener.Name}, opener.Args...))
+		if opener.UseTerm {
+			cmdLine += " [term]"
+		}
+		fmt.Printf("%d) %s # %s\n", num+1, cmdLine, opener.Source)
+	}
+	fmt.Print("c) types a command\n")
+	fmt.Print("\nSelect an option or press ENTER to cancel: ")
+	choice, errRl := readLine()
+	if errRl != nil {
+		return errRl
+	}
+	var opener config.Opener
+	switch choice = strings.TrimSpace(choice); choice {
+	case "":
+		return nil
+	case "c":
+		fmt.Print("Command: ")
+		cmdLine, errRl := readLine()
+		if errRl != nil {
+			return errRl
+		}
+		fields, errSf := config.SplitFields(cmdLine)
+		if errSf != nil {
+			return errSf
+		}
+		if len(fields) == 0 {
+			return nil
+		}
+		fmt.Print("Run it inside the terminal? [y/N] ")
+		answer, errRl := readLine()
+		if errRl != nil {
+			return errRl
+		}
+		opener = config.Opener{
+			Name:    fields[0],
+			Args:    fields[1:],
+			UseTerm: strings.TrimSpace(answer) == "y",
+			Source:  "typed",
+		}
+	default:
+		num, errSa := strconv.Atoi(choice)
+		if errSa != nil || num < 1 || num > len(candidates) {
+			return fmt.Errorf("openWith: error: option '%s' is not valid", choice)
+		}
+		opener = candidates[num-1]
+	}
+	if ext := filepath.Ext(file); ext != "" {
+		fmt.Printf("Remember this choice for '%s' files? [y/N] ", ext)
+		answer, errRl := readLine()
+		if errRl != nil {
+			return errRl
+		}
+		if strings.TrimSpace(answer) == "y" {
+			if errRe := config.Remember(ext, opener); errRe != nil {
+				return errRe
+			}
+		}
+	}
+	_, errSp := spawnProg([]string{file}, config.Position{}, opener)
+	return errSp
+}
+
+// Run selects a file using keyboard interactively, starting at the directory opts.StartDir
+func Run(opts Options) (err error) {
+	defer func() {
+		// the terminal is restored by then, a panic is reported as an error
+		if r := recover(); r != nil {
+			log.Printf("run: panic: %v\n%s", r, debug.Stack())
+			err = fmt.Errorf("run: error: %v\n", r)
+		}
+	}()
 	sf := selectFile{
+		marked:      make(map[string]bool),
+		out:         os.Stdout,
 		linesHeader: 4,
 		linesFooter: 3,
 	}
+	if opts.chooser() {
+		out, restore, errUt := useTTY()
+		if errUt != nil {
+			return errUt
+		}
+		defer restore()
+		sf.out = out
+	}
+	// restoreTerminal undoes whatever setupTerminal did before failing
+	defer restoreTerminal()
+	if errSt := setupTerminal(); errSt != nil {
+		return errSt
+	}
+	keys := newKeyEvents(term.NewKeyReader(os.Stdin))
+	defer keys.stop()
+	chResize := make(chan os.Signal, 1)
+	signal.Notify(chResize, syscall.SIGWINCH)
+	defer signal.Stop(chResize)
+	if opts.StartDir != "" {
+		if errCd := os.Chdir(opts.StartDir); errCd != nil {
+			return errCd
+		}
+	}
+	listed := false
+	// the selected file is kept when the same directory is read again
+	var keepPwd, keepName string
+	keepNum := 0
 	for {
 		var errOg error
 		sf.pwd, errOg = os.Getwd()
@@ -241,58 +444,61 @@ func Run() error {
 			return errOg
 		}
 		var errRd error
-		sf.files, errRd = ioutil.ReadDir(sf.pwd)
+		sf.files, errRd = readDir(sf.pwd, opts)
 		if errRd != nil {
 			return errRd
 		}
 		sf.padInt = utils.CountDigit(len(sf.files))
 		sf.padStr = strconv.Itoa(sf.padInt)
-		if errSc := screen.Clear(); errSc != nil {
-			return errSc
-		}
-		screenSize, errSs := screen.Size()
-		if errSs != nil {
-			return errSs
-		}
-		sf.perPage = screenSize[0]
-		if sf.perPage < sf.linesHeader+sf.linesFooter+1 {
-			return errors.New("sf: error: the terminal window is too small")
-		}
-		if errDh := sf.drawHeader(); errDh != nil {
-			return errDh
-		}
-		var errDb error
-		sf.startOffset = 0
-		sf.linesBody, errDb = sf.drawBody(sf.startOffset, sf.perPage-(sf.linesHeader+sf.linesFooter+1))
-		if errDb != nil {
-			return errDb
+		selected := 0
+		if sf.pwd == keepPwd {
+			if selected = sf.fileIndex(keepName); selected < 0 {
+				selected = keepNum
+			}
 		}
-		sf.page = 1
-		sf.pages = int(math.Ceil(float64(len(sf.files)) / float64(sf.linesBody)))
-		if errDf := sf.drawFooter(sf.startOffset); errDf != nil {
-			return errDf
+		// the first listing fails when the terminal is too small
+		if !listed {
+			if errLa := sf.layout(selected); errLa != nil {
+				return errLa
+			}
+			listed = true
+		} else if errRl := sf.relayout(selected); errRl != nil {
+			return errRl
 		}
-		sf.curPos = sf.linesHeader + 1
-		cursor.ResetModes()
-		cursor.Move(sf.curPos, sf.padInt+1)
 		for keyLoop := true; keyLoop; {
-			key, errKp := utils.KeyPress()
-			if errKp != nil {
-				return errKp
+			var keyName string
+			select {
+			case <-chResize:
+				if errRl := sf.relayout(sf.selected()); errRl != nil {
+					return errRl
+				}
+				continue
+			case <-chRepaint:
+				if errRl := sf.relayout(sf.selected()); errRl != nil {
+					return errRl
+				}
+				continue
+			case event := <-keys.next():
+				keys.received()
+				if event.err != nil {
+					return event.err
+				}
+				keyName = event.name
 			}
-			keyName, errKn := utils.KeyPressName(key)
-			if errKn != nil {
-				return errKn
+			if sf.tooSmall {
+				if keyName == "escape" {
+					return nil
+				}
+				continue
 			}
 			switch keyName {
-			case "?":
+			case "?", "f1":
 				cursor.Move((sf.linesHeader+sf.linesBody+sf.linesFooter)-1, 1)
 				cursor.ClearCurLine()
 				fmt.Print(helpSF())
 				fmt.Print("\nPress ENTER to exit")
-				res := ""
-				if _, errSc := fmt.Scanf("%s", &res); errSc != nil && errSc.Error() != "unexpected newline" {
-					return errSc
+				if _, errRl := readLine(); errRl != nil {
+					return errRl
 				}
 				cursor.Move(sf.linesHeader+1, sf.padInt+1)
 				keyLoop = false
@@ -326,6 +532,11 @@ func Run() error {
 				keyLoop = false
 			case "escape":
 				return nil
+			case "q":
+				if opts.LastDir != "" {
+					return ioutil.WriteFile(opts.LastDir, []byte(sf.pwd+"\n"), 0600)
+				}
+				return nil
 			case "enter", "return", "v":
 				if len(sf.files) == 0 || len(sf.files) <= (sf.curPos+sf.startOffset)-(sf.linesHeader+1) {
 					continue
@@ -353,19 +564,97 @@ func Run() error {
 					}
 					sf.oldPwd = sf.pwd
 					keyLoop = false
+				} else if opts.chooser() {
+					return sf.choose(curFileName.Name(), opts)
 				} else {
-					if errSp := Spawn(curFileName.Name()); errSp != nil {
+					waited, errSp := spawnFile(curFileName.Name())
+					if errSp != nil && waited {
+						// the program ran in the foreground, the listing is not on the screen
+						log.Print(errSp)
+						utils.ErrPrintf("# %s\nPress ENTER to continue", errSp.Error())
+						if _, errRl := readLine(); errRl != nil {
+							return errRl
+						}
+						keyLoop = false
+					} else if errSp != nil {
 						log.Print(errSp)
 						cursor.Move((sf.linesHeader+sf.linesBody+sf.linesFooter)-1, 1)
 						cursor.ClearCurLine()
 						utils.ErrPrintf("# %s", errSp.Error())
 						cursor.Move(sf.curPos, sf.padInt+1)
+					} else if waited {
+						keyLoop = false
+					}
+				}
+			case " ":
+				if !opts.chooser() {
+					cursor.Move((sf.linesHeader+sf.linesBody+sf.linesFooter)-1, 1)
+					cursor.ClearCurLine()
+					utils.ErrPrintf("# error: marking files is only supported with --choose")
+					cursor.Move(sf.curPos, sf.padInt+1)
+					continue
+				}
+				if len(sf.files) == 0 || len(sf.files) <= (sf.curPos+sf.startOffset)-(sf.linesHeader+1) {
+					continue
+				}
+				num := (sf.curPos + sf.startOffset) - (sf.linesHeader + 1)
+				sf.toggleMark(sf.files[num].Name())
+				cursor.Move(sf.curPos, 1)
+				cursor.ClearCurLine()
+				if errDl := sf.drawLine(num); errDl != nil {
+					return errDl
+				}
+				if sf.curPos < sf.linesHeader+sf.linesBody {
+					if errNl := sf.nextLine(); errNl != nil {
+						return errNl
+					}
+				} else {
+					cursor.Move(sf.curPos, sf.padInt+1)
+				}
+			case "o":
+				if len(sf.files) == 0 || len(sf.files) <= (sf.curPos+sf.startOffset)-(sf.linesHeader+1) {
+					continue
+				}
+				curFileName := sf.files[(sf.curPos+sf.startOffset)-(sf.linesHeader+1)].Name()
+				if errOw := sf.openWith(curFileName); errOw != nil {
+					log.Print(errOw)
+					utils.ErrPrintf("# %s\nPress ENTER to continue", errOw.Error())
+					if _, errRl := readLine(); errRl != nil {
+						return errRl
+					}
+				}
+				keyLoop = false
+			case "e":
+				if len(sf.files) == 0 || len(sf.files) <= (sf.curPos+sf.startOffset)-(sf.linesHeader+1) {
+					continue
+				}
+				curFileName := sf.files[(sf.curPos+sf.startOffset)-(sf.linesHeader+1)].Name()
+				if errSc := screen.Clear(); errSc != nil {
+					return errSc
+				}
+				explanation, errEx := Explain(curFileName)
+				fmt.Print(explanation)
+				if errEx != nil {
+					utils.ErrPrintf("%s\n", errEx.Error())
+				}
+				fmt.Print("\nPress ENTER to continue")
+				if _, errRl := readLine(); errRl != nil {
+					return errRl
+				}
+				keyLoop = false
+			case "&":
+				if errSj := showJobs(); errSj != nil {
+					log.Print(errSj)
+					utils.ErrPrintf("# %s\nPress ENTER to continue", errSj.Error())
+					if _, errRl := readLine(); errRl != nil {
+						return errRl
 					}
 				}
-			case "J", "DOWN":
+				keyLoop = false
+			case "J", "shift-down", "ctrl-down", "end":
 				sf.curPos = sf.linesHeader + sf.linesBody
 				cursor.Move(sf.curPos, sf.padInt+1)
-			case "K", "UP":
+			case "K", "shift-up", "ctrl-up", "home":
 				sf.curPos = sf.linesHeader + 1
 				cursor.Move(sf.curPos, sf.padInt+1)
 			case "j", "down":
@@ -388,13 +677,13 @@ func Run() error {
 						return errPp
 					}
 				}
-			case "h", "left":
+			case "h", "left", "pgup":
 				if sf.pages > 1 {
 					if errNp := sf.prevPage(true); errNp != nil {
 						return errNp
 					}
 				}
-			case "l", "right":
+			case "l", "right", "pgdown":
 				if sf.pages > 1 {
 					sf.curPos = sf.linesHeader + sf.linesBody
 					if errNp := sf.nextPage(); errNp != nil {
@@ -410,6 +699,11 @@ func Run() error {
 				cursor.Move(sf.curPos, sf.padInt+1)
 			}
 		}
+		// sf.pwd is still the directory of the listing, a new one starts at the top
+		keepPwd, keepName, keepNum = sf.pwd, "", sf.selected()
+		if keepNum >= 0 && keepNum < len(sf.files) {
+			keepName = sf.files[keepNum].Name()
+		}
 	}
 }
 
@@ -417,28 +711,51 @@ func Run() error {
 func SignalHandler() {
 	chSignal := make(chan os.Signal, 1)
 	chExit := make(chan int)
-	signal.Notify(chSignal, syscall.SIGINT)
+	signal.Notify(chSignal, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGTSTP, syscall.SIGCONT)
 	go func() {
 		for {
 			sig := <-chSignal
+			// the program running in the foreground receives SIGINT too
+			if sig == syscall.SIGINT && atomic.LoadInt32(&foregroundProg) == 1 {
+				continue
+			}
+			// the terminal belongs to the program running in the foreground, if any
+			if sig == syscall.SIGTSTP {
+				if atomic.LoadInt32(&foregroundProg) == 0 {
+					if err := suspendTerminal(); err != nil {
+						log.Print(err)
+					}
+				}
+				if err := syscall.Kill(syscall.Getpid(), syscall.SIGSTOP); err != nil {
+					log.Print(err)
+				}
+				continue
+			}
+			if sig == syscall.SIGCONT {
+				if atomic.LoadInt32(&foregroundProg) == 0 {
+					if err := resumeTerminal(); err != nil {
+						log.Print(err)
+					}
+				}
+				continue
+			}
+			// the message is printed once the normal screen is back
+			if err := finishSF(); err != nil {
+				utils.ErrPrint(err)
+				log.Fatal(err)
+			}
 			msg := fmt.Sprintf("\nsignalHandler: info: recived signal '%s'\n", sig)
 			fmt.Print(msg)
 			log.Print(msg)
 			switch sig {
 			case syscall.SIGINT:
-				if err := finishSF(); err != nil {
-					utils.ErrPrint(err)
-					log.Fatal(err)
-				}
 				chExit <- 0
+			case syscall.SIGTERM, syscall.SIGHUP:
+				chExit <- 128 + int(sig.(syscall.Signal))
 			default:
 				errMsg := fmt.Sprintf("\nsignalHandler: error: unsupported signal '%s'\n", sig)
 				utils.ErrPrint(errMsg)
 				log.Print(errMsg)
-				if err := finishSF(); err != nil {
-					utils.ErrPrint(err)
-					log.Fatal(err)
-				}
 				chExit <- 1
 			}
 		}
@@ -460,32 +777,130 @@ func SetLog() error {
 
 // Spawn runs the program based on their file format
 func Spawn(file string) error {
-	var prgArgs []string
-	var prgAndParams []string
-	ext := filepath.Ext(file)
-	prgOpts, errPe := config.ProgExt(file)
+	_, err := spawnFile(file)
+	return err
+}
+
+// spawnFile runs the program based on their file format, it reports whether
+// sf waited for the program to finish, so the directory may have changed
+func spawnFile(file string) (bool, error) {
+	file, pos := config.SplitPosition(file)
+	opener, errPe := config.ProgExt(file)
 	if errPe != nil {
-		return errPe
+		return false, errPe
 	}
-	if prgOpts["name"] == "" {
-		return fmt.Errorf("spawn: error: file format '%s' is not supported", ext)
+	return spawnProg([]string{file}, pos, opener)
+}
+
+// SpawnFiles runs the programs of several files, the files that share a
+// program are opened in one invocation when the program accepts several files,
+// except the ones given with a position as path:line[:col]
+func SpawnFiles(files []string) error {
+	var openers []config.Opener
+	var groups [][]string
+	var positions []config.Position
+	var errs []string
+	for _, file := range files {
+		file, pos := config.SplitPosition(file)
+		opener, errPe := config.ProgExt(file)
+		if errPe != nil {
+			errs = append(errs, strings.TrimRight(errPe.Error(), "\n"))
+			continue
+		}
+		found := false
+		for num, o := range openers {
+			if pos == (config.Position{}) && positions[num] == (config.Position{}) && o.Multi() && o.Name == opener.Name && strings.Join(o.Args, "\x00") == strings.Join(opener.Args, "\x00") &&
+				o.UseTerm == opener.UseTerm && o.Wait == opener.Wait {
+				groups[num] = append(groups[num], file)
+				found = true
+				break
+			}
+		}
+		if !found {
+			openers = append(openers, opener)
+			groups = append(groups, []string{file})
+			positions = append(positions, pos)
+		}
 	}
-	if len(prgOpts["args"].([]string)) > 0 {
-		for _, value := range prgOpts["args"].([]string) {
-			prgArgs = append(prgArgs, value)
+	for num, opener := range openers {
+		if _, errSp := spawnProg(groups[num], positions[num], opener); errSp != nil {
+			errs = append(errs, strings.TrimRight(errSp.Error(), "\n"))
 		}
 	}
-	prgArgs = append(prgArgs, file)
-	cmd := exec.Command(prgOpts["name"].(string), prgArgs...)
-	if prgOpts["useTerm"] == true {
-		prgAndParams = append(prgAndParams, prgOpts["name"].(string))
-		prgAndParams = append(prgAndParams, prgArgs...)
-		termParams := append(config.TermArgs, prgAndParams...)
-		cmd = exec.Command(config.Term, termParams...)
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n") + "\n")
+	}
+	return nil
+}
+
+// spawnProg runs the program {opener} with the files at the position {pos}, it reports
+// whether sf waited for the program to finish, so the directory may have changed
+func spawnProg(files []string, pos config.Position, opener config.Opener) (bool, error) {
+	mode, cmdLines, errCl := commandLines(files, pos, opener)
+	if errCl != nil {
+		return false, errCl
+	}
+	cmd := exec.Command(cmdLines[0][0], cmdLines[0][1:]...)
+	switch mode {
+	case "inline":
+		return true, runForeground(cmd)
+	case "tmux", "screen":
+		return false, runMux(cmdLines)
 	}
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-	if errCr := cmd.Start(); errCr != nil {
-		return errCr
+	done, errSj := startJob(cmd)
+	if errSj != nil {
+		return false, errSj
+	}
+	if opener.Wait {
+		<-done
+		return true, nil
+	}
+	return false, nil
+}
+
+// commandLines returns how the opener runs the files, "background", "window",
+// "inline", "tmux" or "screen", and the command lines that are executed
+func commandLines(files []string, pos config.Position, opener config.Opener) (string, [][]string, error) {
+	if config.EditorServer {
+		opener = editorServer(opener)
+	}
+	argv := opener.ArgvAt(pos, files...)
+	if !opener.UseTerm {
+		return "background", [][]string{argv}, nil
+	}
+	switch mode := config.CurrentTermMode(); mode {
+	case "inline":
+		return mode, [][]string{argv}, nil
+	case "tmux", "screen":
+		cmdLines, err := muxCommands(mode, argv)
+		return mode, cmdLines, err
+	}
+	termArgv := append(append([]string{config.Term}, config.TermArgs...), argv...)
+	return "window", [][]string{termArgv}, nil
+}
+
+// runForeground runs the program in the current terminal and waits for it to finish
+func runForeground(cmd *exec.Cmd) error {
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	atomic.StoreInt32(&foregroundProg, 1)
+	defer atomic.StoreInt32(&foregroundProg, 0)
+	// sf only sets the terminal back when the program runs from the interactive menu
+	modes := currentModes()
+	if errRt := restoreTerminal(); errRt != nil {
+		return errRt
+	}
+	defer setModes(modes)
+	// sf ignores the keyboard signals while the program runs, catching them instead of
+	// using signal.Ignore because an ignored signal stays ignored in the program too,
+	// the signals that do not fit in the channel are dropped
+	chIgnore := make(chan os.Signal, 1)
+	signal.Notify(chIgnore, syscall.SIGINT, syscall.SIGQUIT)
+	defer signal.Stop(chIgnore)
+	if errCr := cmd.Run(); errCr != nil {
+		return fmt.Errorf("runForeground: error: '%s' %s", cmd.Path, errCr)
 	}
 	return nil
 }
@@ -0,0 +1,192 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package sf
+
+import (
+	"os"
+	"sync"
+)
+
+// local packages
+import (
+	"github.com/gonzaru/sf/cursor"
+	"github.com/gonzaru/sf/screen"
+	"github.com/gonzaru/sf/term"
+	"github.com/gonzaru/sf/utils"
+)
+
+var (
+	// ttyState is the terminal state before entering raw mode, nil while the terminal is not raw
+	ttyState *term.State
+	// ttyAlt reports whether sf is drawing on the alternate screen
+	ttyAlt bool
+	// ttySuspended keeps the terminal modes restored by suspendTerminal
+	ttySuspended ttyModes
+	ttyMutex     sync.Mutex
+	// chRepaint asks Run to draw the listing again
+	chRepaint = make(chan struct{}, 1)
+)
+
+// ttyModes are the terminal modes set by sf
+type ttyModes struct {
+	alt bool
+	raw bool
+}
+
+// currentModes returns the terminal modes set by sf
+func currentModes() ttyModes {
+	ttyMutex.Lock()
+	defer ttyMutex.Unlock()
+	return ttyModes{alt: ttyAlt, raw: ttyState != nil}
+}
+
+// setModes sets again the terminal modes {modes} saved by currentModes, leaving the rest untouched
+func setModes(modes ttyModes) error {
+	ttyMutex.Lock()
+	if modes.alt && !ttyAlt {
+		screen.EnterAltScreen()
+		ttyAlt = true
+	}
+	ttyMutex.Unlock()
+	if modes.raw {
+		return rawMode()
+	}
+	return nil
+}
+
+// setupTerminal switches to the alternate screen and puts the terminal into raw mode
+func setupTerminal() error {
+	ttyMutex.Lock()
+	if !ttyAlt {
+		screen.EnterAltScreen()
+		ttyAlt = true
+	}
+	ttyMutex.Unlock()
+	return rawMode()
+}
+
+// restoreTerminal shows the cursor, switches back to the normal screen and restores
+// the terminal modes, it does nothing when the terminal is already restored
+func restoreTerminal() error {
+	ttyMutex.Lock()
+	if ttyAlt {
+		cursor.Show()
+		screen.ExitAltScreen()
+		ttyAlt = false
+	}
+	ttyMutex.Unlock()
+	return cookedMode()
+}
+
+// rawMode puts the terminal into raw mode to read the keys one by one
+func rawMode() error {
+	ttyMutex.Lock()
+	defer ttyMutex.Unlock()
+	if ttyState != nil {
+		return nil
+	}
+	state, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+	ttyState = state
+	return nil
+}
+
+// cookedMode restores the terminal state saved by rawMode
+func cookedMode() error {
+	ttyMutex.Lock()
+	defer ttyMutex.Unlock()
+	if ttyState == nil {
+		return nil
+	}
+	if err := term.Restore(int(os.Stdin.Fd()), ttyState); err != nil {
+		return err
+	}
+	ttyState = nil
+	return nil
+}
+
+// suspendTerminal restores the terminal remembering its modes, so that resumeTerminal sets them again
+func suspendTerminal() error {
+	modes := currentModes()
+	ttyMutex.Lock()
+	ttySuspended = modes
+	ttyMutex.Unlock()
+	return restoreTerminal()
+}
+
+// resumeTerminal sets the terminal modes restored by suspendTerminal and asks Run to repaint
+func resumeTerminal() error {
+	ttyMutex.Lock()
+	modes := ttySuspended
+	ttySuspended = ttyModes{}
+	ttyMutex.Unlock()
+	if err := setModes(modes); err != nil {
+		return err
+	}
+	select {
+	case chRepaint <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// readLine reads a line in cooked mode, with echo and line editing
+func readLine() (string, error) {
+	if err := cookedMode(); err != nil {
+		return "", err
+	}
+	defer rawMode()
+	return utils.ReadLine()
+}
+
+// keyEvent is a key read from the terminal
+type keyEvent struct {
+	name string
+	err  error
+}
+
+// keyEvents reads the keys in a goroutine, one key for every request, so that the
+// terminal is only read while sf waits for a key and not while a prompt or a
+// program in the foreground use it
+type keyEvents struct {
+	requests chan struct{}
+	events   chan keyEvent
+	pending  bool
+}
+
+// newKeyEvents starts the goroutine that reads the keys with {reader}
+func newKeyEvents(reader *term.KeyReader) *keyEvents {
+	ke := &keyEvents{
+		requests: make(chan struct{}),
+		events:   make(chan keyEvent, 1),
+	}
+	go func() {
+		for range ke.requests {
+			name, err := reader.ReadKey()
+			ke.events <- keyEvent{name: name, err: err}
+		}
+	}()
+	return ke
+}
+
+// next requests the next key, unless it was already requested, and returns the channel it is sent to
+func (ke *keyEvents) next() <-chan keyEvent {
+	if !ke.pending {
+		ke.requests <- struct{}{}
+		ke.pending = true
+	}
+	return ke.events
+}
+
+// received tells that the requested key was taken from the channel
+func (ke *keyEvents) received() {
+	ke.pending = false
+}
+
+// stop ends the goroutine once the pending key, if any, is read
+func (ke *keyEvents) stop() {
+	close(ke.requests)
+}
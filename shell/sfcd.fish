@@ -0,0 +1,20 @@
+# by Gonzaru
+# Distributed under the terms of the GNU General Public License v3
+
+# sfcd runs sf and changes to the directory it was in when leaving with q,
+# copy this file to ~/.config/fish/functions/
+function sfcd --description 'run sf and change to its last directory'
+    set -l tmpdir /tmp
+    set -q TMPDIR; and set tmpdir $TMPDIR
+    set -l lastdir (mktemp $tmpdir/sf-lastdir.XXXXXX); or return
+    command sf --last-dir $lastdir $argv
+    set -l ret $status
+    if test -s $lastdir
+        set -l dir (cat $lastdir)
+        if test -d "$dir"; and test "$dir" != "$PWD"
+            cd $dir; or set ret $status
+        end
+    end
+    rm -f $lastdir
+    return $ret
+end
@@ -0,0 +1,19 @@
+# by Gonzaru
+# Distributed under the terms of the GNU General Public License v3
+
+# sfcd runs sf and changes to the directory it was in when leaving with q,
+# source this file from ~/.bashrc or ~/.zshrc
+sfcd() {
+    local lastdir dir ret
+    lastdir="$(mktemp "${TMPDIR:-/tmp}/sf-lastdir.XXXXXX")" || return
+    command sf --last-dir "$lastdir" "$@"
+    ret=$?
+    if [ -s "$lastdir" ]; then
+        dir="$(cat -- "$lastdir")"
+        if [ -d "$dir" ] && [ "$dir" != "$PWD" ]; then
+            cd -- "$dir" || ret=$?
+        fi
+    fi
+    rm -f -- "$lastdir"
+    return $ret
+}
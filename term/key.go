@@ -0,0 +1,253 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package term
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"unicode/utf8"
+)
+
+// escTimeout is the time in tenths of a second to wait for the rest of an escape
+// sequence, when it expires a lone ESC byte is the Escape key
+const escTimeout = 1
+
+// csiTildeKeys are the keys of the sequences "ESC [ number ~"
+var csiTildeKeys = map[int]string{
+	1:  "home",
+	2:  "insert",
+	3:  "delete",
+	4:  "end",
+	5:  "pgup",
+	6:  "pgdown",
+	7:  "home",
+	8:  "end",
+	11: "f1",
+	12: "f2",
+	13: "f3",
+	14: "f4",
+	15: "f5",
+	17: "f6",
+	18: "f7",
+	19: "f8",
+	20: "f9",
+	21: "f10",
+	23: "f11",
+	24: "f12",
+}
+
+// csiFinalKeys are the keys of the sequences "ESC [ letter" and "ESC O letter"
+var csiFinalKeys = map[byte]string{
+	'A': "up",
+	'B': "down",
+	'C': "right",
+	'D': "left",
+	'F': "end",
+	'H': "home",
+	'P': "f1",
+	'Q': "f2",
+	'R': "f3",
+	'S': "f4",
+	'Z': "backtab",
+}
+
+// KeyReader decodes the keys read from a terminal in raw mode, the bytes that
+// belong to the next keys are kept until they are requested
+type KeyReader struct {
+	file *os.File
+	buf  []byte
+}
+
+// NewKeyReader returns a KeyReader that reads from {file}
+func NewKeyReader(file *os.File) *KeyReader {
+	return &KeyReader{file: file}
+}
+
+// ReadKey returns the name of the next key: a character such as "j" or "ä", "enter",
+// "escape", "up", "pgdown", "f1", and modified keys such as "ctrl-x", "alt-x" or "shift-up"
+func (kr *KeyReader) ReadKey() (string, error) {
+	if len(kr.buf) == 0 {
+		if err := kr.fill(false); err != nil {
+			return "", err
+		}
+	}
+	for {
+		name, n := decodeKey(kr.buf, false)
+		if n == 0 {
+			// the sequence is incomplete, a timeout means it was a key on its own
+			if err := kr.fill(true); err == errTimeout {
+				name, n = decodeKey(kr.buf, true)
+			} else if err != nil {
+				return "", err
+			} else {
+				continue
+			}
+		}
+		kr.buf = kr.buf[n:]
+		return name, nil
+	}
+}
+
+// errTimeout is returned by fill when no byte arrived in time
+var errTimeout = errors.New("keyReader: error: timeout")
+
+// fill appends the available bytes to the buffer, waiting at most escTimeout when {timed}
+func (kr *KeyReader) fill(timed bool) error {
+	data := make([]byte, 64)
+	var n int
+	var err error
+	if timed {
+		n, err = readTimeout(kr.file, data, escTimeout)
+		if err == io.EOF || (err == nil && n == 0) {
+			return errTimeout
+		}
+	} else {
+		n, err = kr.file.Read(data)
+	}
+	if err != nil {
+		return err
+	}
+	kr.buf = append(kr.buf, data[:n]...)
+	return nil
+}
+
+// readTimeout reads from the terminal {file} waiting at most {tenths} of a second
+func readTimeout(file *os.File, data []byte, tenths uint8) (int, error) {
+	fd := int(file.Fd())
+	termios, err := getTermios(fd)
+	if err != nil {
+		return 0, err
+	}
+	timed := termios
+	timed.Cc[syscall.VMIN] = 0
+	timed.Cc[syscall.VTIME] = tenths
+	if errSt := setTermios(fd, &timed); errSt != nil {
+		return 0, errSt
+	}
+	defer setTermios(fd, &termios)
+	return file.Read(data)
+}
+
+// decodeKey returns the name of the key at the start of {buf} and its length, the length
+// is 0 when {buf} only holds the start of a sequence and {final} is false
+func decodeKey(buf []byte, final bool) (string, int) {
+	c := buf[0]
+	switch {
+	case c == 0x1b:
+		return decodeEscape(buf, final)
+	case c == '\r' || c == '\n':
+		return "enter", 1
+	case c == '\t':
+		return "tab", 1
+	case c == 0x7f || c == 0x08:
+		return "backspace", 1
+	case c == 0:
+		return "ctrl-space", 1
+	case c < 0x20:
+		// the control characters are the keys from '@' to '_' with ctrl pressed
+		return "ctrl-" + strings.ToLower(string(rune(c+'@'))), 1
+	case c < utf8.RuneSelf:
+		return string(c), 1
+	}
+	if !utf8.FullRune(buf) && !final {
+		return "", 0
+	}
+	r, size := utf8.DecodeRune(buf)
+	return string(r), size
+}
+
+// decodeEscape decodes the key sequences that start with ESC
+func decodeEscape(buf []byte, final bool) (string, int) {
+	if len(buf) == 1 {
+		if final {
+			return "escape", 1
+		}
+		return "", 0
+	}
+	switch buf[1] {
+	case '[':
+		if name, n := decodeCSI(buf); n > 0 {
+			return name, n
+		}
+		if final {
+			return "alt-[", 2
+		}
+		return "", 0
+	case 'O':
+		if len(buf) > 2 {
+			if name, found := csiFinalKeys[buf[2]]; found {
+				return name, 3
+			}
+			return "alt-O", 2
+		}
+		if final {
+			return "alt-O", 2
+		}
+		return "", 0
+	case 0x1b:
+		return "escape", 1
+	}
+	name, n := decodeKey(buf[1:], final)
+	if n == 0 {
+		return "", 0
+	}
+	return "alt-" + name, n + 1
+}
+
+// decodeCSI decodes a control sequence "ESC [ parameters final", its length is 0 when incomplete
+func decodeCSI(buf []byte) (string, int) {
+	end := -1
+	for i := 2; i < len(buf); i++ {
+		if buf[i] >= 0x40 && buf[i] <= 0x7e {
+			end = i
+			break
+		}
+		if buf[i] < 0x20 || buf[i] > 0x3f {
+			// not a control sequence, the bytes are decoded as separate keys
+			return "alt-[", 2
+		}
+	}
+	if end == -1 {
+		return "", 0
+	}
+	params := strings.Split(string(buf[2:end]), ";")
+	var name string
+	if buf[end] == '~' {
+		code, _ := strconv.Atoi(params[0])
+		name = csiTildeKeys[code]
+	} else {
+		name = csiFinalKeys[buf[end]]
+	}
+	if name == "" {
+		return strings.Replace(string(buf[:end+1]), "\x1b", "^[", 1), end + 1
+	}
+	if len(params) > 1 {
+		name = modifiers(params[1]) + name
+	}
+	return name, end + 1
+}
+
+// modifiers returns the prefix of the xterm modifier parameter {param}
+func modifiers(param string) string {
+	mod, err := strconv.Atoi(param)
+	if err != nil || mod < 2 {
+		return ""
+	}
+	mod--
+	var prefix string
+	if mod&4 != 0 {
+		prefix += "ctrl-"
+	}
+	if mod&2 != 0 {
+		prefix += "alt-"
+	}
+	if mod&1 != 0 {
+		prefix += "shift-"
+	}
+	return prefix
+}
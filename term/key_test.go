@@ -0,0 +1,103 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package term
+
+import "testing"
+
+func TestDecodeKey(t *testing.T) {
+	tests := []struct {
+		in    string
+		final bool
+		name  string
+		n     int
+	}{
+		// single bytes
+		{"j", false, "j", 1},
+		{"jk", false, "j", 1},
+		{"\r", false, "enter", 1},
+		{"\n", false, "enter", 1},
+		{"\t", false, "tab", 1},
+		{"\x7f", false, "backspace", 1},
+		{"\x00", false, "ctrl-space", 1},
+		{"\x01", false, "ctrl-a", 1},
+		{"\x1a", false, "ctrl-z", 1},
+		{"\x1c", false, "ctrl-\\", 1},
+		{"\x1d", false, "ctrl-]", 1},
+		{"\x1e", false, "ctrl-^", 1},
+		{"\x1f", false, "ctrl-_", 1},
+		// UTF-8
+		{"ä", false, "ä", 2},
+		{"\xc3", false, "", 0},
+		{"\xe2\x82", false, "", 0},
+		{"\xe2\x82\xac", false, "€", 3},
+		{"\xc3", true, "�", 1},
+		// ESC timeout
+		{"\x1b", false, "", 0},
+		{"\x1b", true, "escape", 1},
+		{"\x1b\x1b", false, "escape", 1},
+		{"\x1b[", false, "", 0},
+		{"\x1b[", true, "alt-[", 2},
+		{"\x1bO", false, "", 0},
+		{"\x1bO", true, "alt-O", 2},
+		// alt
+		{"\x1bx", false, "alt-x", 2},
+		{"\x1b\r", false, "alt-enter", 2},
+		{"\x1b\xc3\xa4", false, "alt-ä", 3},
+		// CSI
+		{"\x1b[A", false, "up", 3},
+		{"\x1b[Bj", false, "down", 3},
+		{"\x1b[C", false, "right", 3},
+		{"\x1b[D", false, "left", 3},
+		{"\x1b[H", false, "home", 3},
+		{"\x1b[F", false, "end", 3},
+		{"\x1b[Z", false, "backtab", 3},
+		{"\x1b[1~", false, "home", 4},
+		{"\x1b[3~", false, "delete", 4},
+		{"\x1b[4~", false, "end", 4},
+		{"\x1b[5~", false, "pgup", 4},
+		{"\x1b[6~", false, "pgdown", 4},
+		{"\x1b[15~", false, "f5", 5},
+		{"\x1b[24~", false, "f12", 5},
+		{"\x1b[6", false, "", 0},
+		{"\x1b[99~", false, "^[[99~", 5},
+		// modifiers
+		{"\x1b[1;2A", false, "shift-up", 6},
+		{"\x1b[1;3B", false, "alt-down", 6},
+		{"\x1b[1;5A", false, "ctrl-up", 6},
+		{"\x1b[1;6D", false, "ctrl-shift-left", 6},
+		{"\x1b[1;8C", false, "ctrl-alt-shift-right", 6},
+		{"\x1b[5;5~", false, "ctrl-pgup", 6},
+		{"\x1b[3;2~", false, "shift-delete", 6},
+		// SS3
+		{"\x1bOA", false, "up", 3},
+		{"\x1bOH", false, "home", 3},
+		{"\x1bOP", false, "f1", 3},
+		{"\x1bOS", false, "f4", 3},
+		{"\x1bOx", false, "alt-O", 2},
+	}
+	for _, test := range tests {
+		name, n := decodeKey([]byte(test.in), test.final)
+		if name != test.name || n != test.n {
+			t.Errorf("decodeKey(%q, %v) = %q, %d; want %q, %d", test.in, test.final, name, n, test.name, test.n)
+		}
+	}
+}
+
+func TestModifiers(t *testing.T) {
+	tests := map[string]string{
+		"":  "",
+		"1": "",
+		"2": "shift-",
+		"3": "alt-",
+		"4": "alt-shift-",
+		"5": "ctrl-",
+		"7": "ctrl-alt-",
+		"x": "",
+	}
+	for param, want := range tests {
+		if got := modifiers(param); got != want {
+			t.Errorf("modifiers(%q) = %q; want %q", param, got, want)
+		}
+	}
+}
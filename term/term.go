@@ -0,0 +1,87 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+package term
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+// State is the terminal state saved before entering raw mode
+type State struct {
+	termios syscall.Termios
+}
+
+// winsize is the terminal window size returned by TIOCGWINSZ
+type winsize struct {
+	rows   uint16
+	cols   uint16
+	xPixel uint16
+	yPixel uint16
+}
+
+// ioctl performs the ioctl request {req} on the file descriptor {fd}
+func ioctl(fd int, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// getTermios returns the terminal attributes of {fd}
+func getTermios(fd int) (syscall.Termios, error) {
+	var termios syscall.Termios
+	err := ioctl(fd, ioctlGetTermios, unsafe.Pointer(&termios))
+	return termios, err
+}
+
+// setTermios sets the terminal attributes of {fd}
+func setTermios(fd int, termios *syscall.Termios) error {
+	return ioctl(fd, ioctlSetTermios, unsafe.Pointer(termios))
+}
+
+// IsTerminal reports whether {fd} is a terminal
+func IsTerminal(fd int) bool {
+	_, err := getTermios(fd)
+	return err == nil
+}
+
+// MakeRaw puts the terminal {fd} into raw mode and returns its previous state, the keys
+// are read one by one without echo, but the signal keys (^C, ^Z) and the output
+// processing that turns "\n" into "\r\n" are kept
+func MakeRaw(fd int) (*State, error) {
+	termios, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+	state := &State{termios: termios}
+	termios.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.IXON
+	termios.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.IEXTEN
+	termios.Cflag &^= syscall.CSIZE | syscall.PARENB
+	termios.Cflag |= syscall.CS8
+	termios.Cc[syscall.VMIN] = 1
+	termios.Cc[syscall.VTIME] = 0
+	if errSt := setTermios(fd, &termios); errSt != nil {
+		return nil, errSt
+	}
+	return state, nil
+}
+
+// Restore sets the terminal {fd} back to the state {state}
+func Restore(fd int, state *State) error {
+	if state == nil {
+		return errors.New("restore: error: no terminal state to restore")
+	}
+	return setTermios(fd, &state.termios)
+}
+
+// GetSize returns the number of rows and columns of the terminal {fd}
+func GetSize(fd int) (int, int, error) {
+	var ws winsize
+	if err := ioctl(fd, syscall.TIOCGWINSZ, unsafe.Pointer(&ws)); err != nil {
+		return 0, 0, err
+	}
+	return int(ws.rows), int(ws.cols), nil
+}
@@ -0,0 +1,14 @@
+// by Gonzaru
+// Distributed under the terms of the GNU General Public License v3
+
+//go:build linux
+// +build linux
+
+package term
+
+import "syscall"
+
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
@@ -5,10 +5,9 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"os/exec"
-	"runtime"
 )
 
 // CountDigit counts the number of digits in a number
@@ -60,66 +59,25 @@ func FileIndicator(file string) (string, error) {
 	return symbol, nil
 }
 
-// KeyPress gets the pressed key
-func KeyPress() ([]byte, error) {
-	key := make([]byte, 3, 3)
-	fileFlag := "-f"
-	if runtime.GOOS == "linux" {
-		fileFlag = "-F"
-	}
-	if errCs := exec.Command("stty", fileFlag, "/dev/tty", "cbreak", "min", "1").Run(); errCs != nil {
-		return nil, errCs
-	}
-	if errCs := exec.Command("stty", fileFlag, "/dev/tty", "-echo").Run(); errCs != nil {
-		return nil, errCs
-	}
-	defer func() {
-		if errCs := exec.Command("stty", fileFlag, "/dev/tty", "echo").Run(); errCs != nil {
-			ErrPrint(errCs)
-			log.Fatal(errCs)
+// ReadLine reads a line from the standard input, without the trailing newline
+func ReadLine() (string, error) {
+	var line []byte
+	char := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(char)
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				break
+			}
+			return "", err
 		}
-		cmdSs := exec.Command("stty", "sane")
-		cmdSs.Stdin = os.Stdin
-		if errCr := cmdSs.Run(); errCr != nil {
-			ErrPrint(errCr)
-			log.Fatal(errCr)
+		if n == 0 {
+			continue
 		}
-	}()
-	if _, errSr := os.Stdin.Read(key); errSr != nil {
-		return nil, errSr
-	}
-	return key, nil
-}
-
-// KeyPressName returns the name of pressed key
-func KeyPressName(key []byte) (string, error) {
-	var keyName string
-	keySize := 3
-	if len(key) != keySize {
-		return "", fmt.Errorf("keyPressName: error: key needs to be size %d", keySize)
-	}
-	if key[0] != 0 && key[1] == 0 && key[2] == 0 {
-		if key[0] == 27 {
-			keyName = "escape"
-		} else if key[0] == 10 {
-			keyName = "enter"
-		} else {
-			keyName = string(key[0])
+		if char[0] == '\n' {
+			break
 		}
-	} else if key[0] == 27 && key[1] == 91 && key[2] == 65 {
-		keyName = "up"
-	} else if key[0] == 27 && key[1] == 91 && key[2] == 66 {
-		keyName = "down"
-	} else if key[0] == 27 && key[1] == 91 && key[2] == 67 {
-		keyName = "right"
-	} else if key[0] == 27 && key[1] == 91 && key[2] == 68 {
-		keyName = "left"
-	} else if key[0] == 59 && key[1] == 50 && key[2] == 65 { // <S-Up>
-		keyName = "UP"
-	} else if key[0] == 59 && key[1] == 50 && key[2] == 66 { // <S-Down>
-		keyName = "DOWN"
-	} else {
-		keyName = string(key)
+		line = append(line, char[0])
 	}
-	return keyName, nil
+	return string(line), nil
 }